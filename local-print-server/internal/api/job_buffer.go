@@ -3,18 +3,50 @@ package api
 import (
 	"sync"
 	"time"
+
+	"github.com/jetsetgo/local-print-server/internal/jobs"
 )
 
 // JobRecord represents a completed print job
 type JobRecord struct {
-	ID          string     `json:"id"`
-	PrinterID   string     `json:"printer_id"`
-	PrinterName string     `json:"printer_name"`
-	Status      string     `json:"status"` // completed, failed, printing, pending
-	DataSize    int        `json:"data_size"`
-	CreatedAt   time.Time  `json:"created_at"`
-	CompletedAt *time.Time `json:"completed_at,omitempty"`
-	Error       string     `json:"error,omitempty"`
+	ID            string     `json:"id"`
+	PrinterID     string     `json:"printer_id"`
+	PrinterName   string     `json:"printer_name"`
+	Status        string     `json:"status"` // See jobs.Spooler.OnStatus for the lifecycle
+	DataSize      int        `json:"data_size"`
+	Copies        int        `json:"copies,omitempty"`
+	CopiesPrinted int        `json:"copies_printed"`
+	BytesSent     int        `json:"bytes_sent,omitempty"` // Written to the printer so far, across copies
+	BytesTotal    int        `json:"bytes_total,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	PrintAt       *time.Time `json:"print_at,omitempty"` // Scheduled jobs only
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	ReprintOf     string     `json:"reprint_of,omitempty"` // Original job ID, for reprints
+	Reprintable   bool       `json:"reprintable"`          // Payload still held for reprinting
+
+	// When the job entered each status, and reached each stage of its latest print attempt
+	StatusAt map[string]time.Time `json:"status_at,omitempty"`
+	Timing   jobs.Timing          `json:"timing"`
+}
+
+// clone copies a record so it can be read after the buffer's lock is released
+func (r JobRecord) clone() JobRecord {
+	if r.StatusAt != nil {
+		statusAt := make(map[string]time.Time, len(r.StatusAt))
+		for k, v := range r.StatusAt {
+			statusAt[k] = v
+		}
+		r.StatusAt = statusAt
+	}
+	return r
+}
+
+// storedPayload is a finished job's data, kept for reprinting
+type storedPayload struct {
+	data     []byte
+	options  jobs.Options
+	storedAt time.Time
 }
 
 // JobBuffer is a thread-safe ring buffer for job records
@@ -22,21 +54,45 @@ type JobBuffer struct {
 	mu      sync.RWMutex
 	entries []JobRecord
 	cap     int
+
+	// Payloads of finished jobs, bounded by total size and age
+	payloads     map[string]*storedPayload
+	payloadBytes int
+	payloadMax   int
+	payloadAge   time.Duration
 }
 
 // NewJobBuffer creates a new job buffer with the given capacity
 func NewJobBuffer(capacity int) *JobBuffer {
 	return &JobBuffer{
-		entries: make([]JobRecord, 0, capacity),
-		cap:     capacity,
+		entries:    make([]JobRecord, 0, capacity),
+		cap:        capacity,
+		payloads:   make(map[string]*storedPayload),
+		payloadMax: 16 << 20,
+		payloadAge: 24 * time.Hour,
 	}
 }
 
+// SetPayloadLimits bounds the payloads kept for reprinting
+func (jb *JobBuffer) SetPayloadLimits(maxBytes int, maxAge time.Duration) {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+
+	jb.payloadMax = maxBytes
+	jb.payloadAge = maxAge
+	jb.prunePayloads(time.Now())
+}
+
 // Add adds a job record to the buffer
 func (jb *JobBuffer) Add(job JobRecord) {
 	jb.mu.Lock()
 	defer jb.mu.Unlock()
 
+	if job.StatusAt == nil && job.Status != "" {
+		job.StatusAt = map[string]time.Time{job.Status: job.CreatedAt}
+	}
+	// Payloads outlive their records, within their own limits, so jobs found in the
+	// history can still be reprinted
 	if len(jb.entries) >= jb.cap {
 		copy(jb.entries, jb.entries[1:])
 		jb.entries[len(jb.entries)-1] = job
@@ -50,14 +106,88 @@ func (jb *JobBuffer) Entries() []JobRecord {
 	jb.mu.RLock()
 	defer jb.mu.RUnlock()
 
+	now := time.Now()
 	result := make([]JobRecord, len(jb.entries))
 	// Reverse order so newest is first
 	for i, j := 0, len(jb.entries)-1; j >= 0; i, j = i+1, j-1 {
-		result[i] = jb.entries[j]
+		result[i] = jb.entries[j].clone()
+		if p, ok := jb.payloads[result[i].ID]; ok && now.Sub(p.storedAt) < jb.payloadAge {
+			result[i].Reprintable = true
+		}
 	}
 	return result
 }
 
+// Get returns a job record by ID
+func (jb *JobBuffer) Get(jobID string) (JobRecord, bool) {
+	jb.mu.RLock()
+	defer jb.mu.RUnlock()
+
+	for i := len(jb.entries) - 1; i >= 0; i-- {
+		if jb.entries[i].ID == jobID {
+			return jb.entries[i].clone(), true
+		}
+	}
+	return JobRecord{}, false
+}
+
+// StorePayload keeps a finished job's data for reprinting. The oldest payloads
+// are dropped to stay within the size limit; payloads over the limit are not kept.
+func (jb *JobBuffer) StorePayload(jobID string, data []byte, options jobs.Options) {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+
+	if len(data) == 0 || len(data) > jb.payloadMax {
+		return
+	}
+	if _, ok := jb.payloads[jobID]; ok {
+		return
+	}
+	jb.payloads[jobID] = &storedPayload{data: data, options: options, storedAt: time.Now()}
+	jb.payloadBytes += len(data)
+	jb.prunePayloads(time.Now())
+}
+
+// Payload returns a job's stored data and options, if still held
+func (jb *JobBuffer) Payload(jobID string) ([]byte, jobs.Options, bool) {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+
+	jb.prunePayloads(time.Now())
+	p, ok := jb.payloads[jobID]
+	if !ok {
+		return nil, jobs.Options{}, false
+	}
+	return p.data, p.options, true
+}
+
+// prunePayloads drops expired payloads, then the oldest until within the size limit.
+// Caller must hold mu.
+func (jb *JobBuffer) prunePayloads(now time.Time) {
+	for id, p := range jb.payloads {
+		if now.Sub(p.storedAt) >= jb.payloadAge {
+			jb.dropPayload(id)
+		}
+	}
+	for jb.payloadBytes > jb.payloadMax && len(jb.payloads) > 0 {
+		oldest := ""
+		for id, p := range jb.payloads {
+			if oldest == "" || p.storedAt.Before(jb.payloads[oldest].storedAt) {
+				oldest = id
+			}
+		}
+		jb.dropPayload(oldest)
+	}
+}
+
+// dropPayload forgets a job's payload. Caller must hold mu.
+func (jb *JobBuffer) dropPayload(jobID string) {
+	if p, ok := jb.payloads[jobID]; ok {
+		jb.payloadBytes -= len(p.data)
+		delete(jb.payloads, jobID)
+	}
+}
+
 // UpdateStatus updates the status of a job by ID
 func (jb *JobBuffer) UpdateStatus(jobID, status, errMsg string) {
 	jb.mu.Lock()
@@ -66,10 +196,17 @@ func (jb *JobBuffer) UpdateStatus(jobID, status, errMsg string) {
 	for i := len(jb.entries) - 1; i >= 0; i-- {
 		if jb.entries[i].ID == jobID {
 			jb.entries[i].Status = status
+			if jb.entries[i].StatusAt == nil {
+				jb.entries[i].StatusAt = make(map[string]time.Time)
+			}
+			jb.entries[i].StatusAt[status] = time.Now()
 			if errMsg != "" {
 				jb.entries[i].Error = errMsg
+			} else if status == "completed" {
+				// Clear errors from earlier attempts (e.g. while queued)
+				jb.entries[i].Error = ""
 			}
-			if status == "completed" || status == "failed" {
+			if status == "completed" || status == "failed" || status == "cancelled" || status == "expired" {
 				now := time.Now()
 				jb.entries[i].CompletedAt = &now
 			}
@@ -77,3 +214,56 @@ func (jb *JobBuffer) UpdateStatus(jobID, status, errMsg string) {
 		}
 	}
 }
+
+// UpdateTiming records when a job reached each stage of printing
+func (jb *JobBuffer) UpdateTiming(jobID string, timing jobs.Timing) {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+
+	for i := len(jb.entries) - 1; i >= 0; i-- {
+		if jb.entries[i].ID == jobID {
+			jb.entries[i].Timing = timing
+			return
+		}
+	}
+}
+
+// SetPrintAt records when a scheduled job is due
+func (jb *JobBuffer) SetPrintAt(jobID string, at time.Time) {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+
+	for i := len(jb.entries) - 1; i >= 0; i-- {
+		if jb.entries[i].ID == jobID {
+			jb.entries[i].PrintAt = &at
+			return
+		}
+	}
+}
+
+// UpdateProgress records how many bytes of a job have been written to the printer
+func (jb *JobBuffer) UpdateProgress(jobID string, sent, total int) {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+
+	for i := len(jb.entries) - 1; i >= 0; i-- {
+		if jb.entries[i].ID == jobID {
+			jb.entries[i].BytesSent = sent
+			jb.entries[i].BytesTotal = total
+			return
+		}
+	}
+}
+
+// UpdateCopies records how many copies of a job have been printed
+func (jb *JobBuffer) UpdateCopies(jobID string, printed int) {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+
+	for i := len(jb.entries) - 1; i >= 0; i-- {
+		if jb.entries[i].ID == jobID {
+			jb.entries[i].CopiesPrinted = printed
+			return
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// segmentMaxBytes is the size at which the history starts a new segment
+const segmentMaxBytes = 1 << 20
+
+// historyIndexFile summarises the segments in the history directory
+const historyIndexFile = "index.json"
+
+// HistoryLimits bounds the job history. Whole segments are dropped, oldest first, so up
+// to one segment's worth of records beyond a limit may be kept. Zero means no limit.
+type HistoryLimits struct {
+	MaxRecords int
+	MaxAge     time.Duration // Since the segment was last written
+	MaxBytes   int64
+}
+
+// historySegment describes one JSON-lines segment file
+type historySegment struct {
+	File       string    `json:"file"`
+	Records    int       `json:"records"`
+	Bytes      int64     `json:"bytes"`
+	MinCreated time.Time `json:"min_created"` // Span of the records' created_at,
+	MaxCreated time.Time `json:"max_created"` // so time-range queries can skip the segment
+	Written    time.Time `json:"written"`     // Last append
+}
+
+// overlaps reports whether the segment may hold records created in [from, to)
+func (seg *historySegment) overlaps(from, to time.Time) bool {
+	if seg.Records == 0 {
+		return false
+	}
+	if !from.IsZero() && seg.MaxCreated.Before(from) {
+		return false
+	}
+	if !to.IsZero() && !seg.MinCreated.Before(to) {
+		return false
+	}
+	return true
+}
+
+// add updates the summary for an appended record
+func (seg *historySegment) add(rec JobRecord, size int64, now time.Time) {
+	if seg.Records == 0 || rec.CreatedAt.Before(seg.MinCreated) {
+		seg.MinCreated = rec.CreatedAt
+	}
+	if seg.Records == 0 || rec.CreatedAt.After(seg.MaxCreated) {
+		seg.MaxCreated = rec.CreatedAt
+	}
+	seg.Records++
+	seg.Bytes += size
+	seg.Written = now
+}
+
+// JobHistory is an append-only, file-backed log of finished jobs, so jobs can still be
+// looked up after they leave the in-memory JobBuffer or the server restarts. Records are
+// JSON lines in segment files; index.json summarises each segment so retention and
+// time-range queries work on whole segments without reading them.
+type JobHistory struct {
+	dir        string
+	limits     HistoryLimits
+	segmentMax int64
+
+	mu       sync.Mutex
+	segments []*historySegment // Oldest first; records are appended to the last one
+	nextSeg  int
+}
+
+// OpenJobHistory opens the history in dir, repairing the index from the segment files
+// if it is missing or out of date
+func OpenJobHistory(dir string, limits HistoryLimits) (*JobHistory, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	h := &JobHistory{dir: dir, limits: limits, segmentMax: segmentMaxBytes}
+	if limits.MaxBytes > 0 && limits.MaxBytes/4 < h.segmentMax {
+		// Keep several segments within the byte limit, so retention isn't all-or-nothing
+		h.segmentMax = max(limits.MaxBytes/4, 4096)
+	}
+
+	if err := h.load(); err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	h.applyRetention(time.Now())
+	h.saveIndex()
+	h.mu.Unlock()
+	return h, nil
+}
+
+// load reads the index and reconciles it with the segment files on disk
+func (h *JobHistory) load() error {
+	indexed := make(map[string]*historySegment)
+	if data, err := os.ReadFile(filepath.Join(h.dir, historyIndexFile)); err == nil {
+		var segments []*historySegment
+		if json.Unmarshal(data, &segments) == nil {
+			for _, seg := range segments {
+				indexed[seg.File] = seg
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read history index: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(h.dir, "*.jsonl"))
+	if err != nil {
+		return fmt.Errorf("failed to list history segments: %w", err)
+	}
+	sort.Strings(files) // Zero-padded sequence numbers sort oldest first
+
+	for _, path := range files {
+		name := filepath.Base(path)
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		seg, ok := indexed[name]
+		if !ok || seg.Bytes != info.Size() {
+			// Not indexed yet, or appended to without the index being saved
+			if seg, err = scanSegment(path); err != nil {
+				log.Printf("Skipping unreadable history segment %s: %v", name, err)
+				continue
+			}
+		}
+		h.segments = append(h.segments, seg)
+
+		var n int
+		if _, err := fmt.Sscanf(strings.TrimSuffix(name, ".jsonl"), "%d", &n); err == nil && n >= h.nextSeg {
+			h.nextSeg = n + 1
+		}
+	}
+	return nil
+}
+
+// scanSegment rebuilds a segment's summary from its file
+func scanSegment(path string) (*historySegment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	seg := &historySegment{File: filepath.Base(path), Written: info.ModTime()}
+	err = readSegment(path, func(rec JobRecord, size int64) {
+		seg.add(rec, 0, seg.Written)
+	})
+	seg.Bytes = info.Size()
+	return seg, err
+}
+
+// readSegment calls fn for each record in a segment file, in order
+func readSegment(path string, fn func(rec JobRecord, size int64)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var rec JobRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue // Skip a line torn by a crash mid-write
+		}
+		fn(rec, int64(len(scanner.Bytes())+1))
+	}
+	return scanner.Err()
+}
+
+// Append records a finished job
+func (h *JobHistory) Append(rec JobRecord) error {
+	rec.Reprintable = false
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if len(h.segments) == 0 || h.segments[len(h.segments)-1].Bytes >= h.segmentMax {
+		h.segments = append(h.segments, &historySegment{File: fmt.Sprintf("%08d.jsonl", h.nextSeg)})
+		h.nextSeg++
+	}
+	seg := h.segments[len(h.segments)-1]
+
+	f, err := os.OpenFile(filepath.Join(h.dir, seg.File), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history segment: %w", err)
+	}
+	_, err = f.Write(line)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write history segment: %w", err)
+	}
+
+	seg.add(rec, int64(len(line)), now)
+	h.applyRetention(now)
+	h.saveIndex()
+	return nil
+}
+
+// Find returns up to limit of the newest records matching q (including its cursor),
+// newest first. Records whose ID is in skip are left out, for jobs the caller holds a
+// newer copy of. A job recorded more than once is matched on its latest record.
+//
+// Segments are read newest first by the records they span, and reading stops once the
+// remaining segments only hold records older than the limit-th match, so a first page
+// reads one or two segments however long the history is.
+func (h *JobHistory) Find(q JobQuery, limit int, skip map[string]bool) ([]JobRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var order []int
+	for i, seg := range h.segments {
+		if seg.overlaps(q.From, q.To) {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return h.segments[order[a]].MaxCreated.After(h.segments[order[b]].MaxCreated)
+	})
+
+	latest := make(map[string]int) // Segment holding each job's latest record seen
+	matches := make(map[string]JobRecord)
+	var page []JobRecord
+	for _, i := range order {
+		seg := h.segments[i]
+		if len(page) >= limit && seg.MaxCreated.Before(page[limit-1].CreatedAt) {
+			break
+		}
+
+		err := readSegment(filepath.Join(h.dir, seg.File), func(rec JobRecord, _ int64) {
+			if skip[rec.ID] {
+				return
+			}
+			if j, ok := latest[rec.ID]; ok && j > i {
+				return // A later segment already gave its latest record
+			}
+			latest[rec.ID] = i
+			if q.match(rec) && (q.after == nil || q.after.before(rec)) {
+				matches[rec.ID] = rec
+			} else {
+				delete(matches, rec.ID)
+			}
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read history segment %s: %w", seg.File, err)
+		}
+
+		page = page[:0]
+		for _, rec := range matches {
+			page = append(page, rec)
+		}
+		sort.Slice(page, func(a, b int) bool { return newerJob(page[a], page[b]) })
+	}
+	if len(page) > limit {
+		page = page[:limit]
+	}
+	return page, nil
+}
+
+// Recent returns up to n of the most recently recorded jobs, oldest first
+func (h *JobHistory) Recent(n int) ([]JobRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Read just enough segments from the newest end
+	first, count := len(h.segments), 0
+	for first > 0 && count < n {
+		first--
+		count += h.segments[first].Records
+	}
+
+	var records []JobRecord
+	index := make(map[string]int)
+	for _, seg := range h.segments[first:] {
+		err := readSegment(filepath.Join(h.dir, seg.File), func(rec JobRecord, _ int64) {
+			if i, ok := index[rec.ID]; ok {
+				// Move a re-recorded job to its latest position
+				records[i].ID = ""
+			}
+			index[rec.ID] = len(records)
+			records = append(records, rec)
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read history segment %s: %w", seg.File, err)
+		}
+	}
+
+	result := make([]JobRecord, 0, n)
+	for i := len(records) - 1; i >= 0 && len(result) < n; i-- {
+		if records[i].ID != "" {
+			result = append(result, records[i])
+		}
+	}
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result, nil
+}
+
+// Size returns the number of records and bytes held
+func (h *JobHistory) Size() (records int, bytes int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, seg := range h.segments {
+		records += seg.Records
+		bytes += seg.Bytes
+	}
+	return records, bytes
+}
+
+// applyRetention drops the oldest segments beyond the limits. The segment being
+// appended to is always kept. Caller must hold mu.
+func (h *JobHistory) applyRetention(now time.Time) {
+	var records int
+	var bytes int64
+	for _, seg := range h.segments {
+		records += seg.Records
+		bytes += seg.Bytes
+	}
+
+	for len(h.segments) > 1 {
+		oldest := h.segments[0]
+		overCount := h.limits.MaxRecords > 0 && records-oldest.Records >= h.limits.MaxRecords
+		overBytes := h.limits.MaxBytes > 0 && bytes > h.limits.MaxBytes
+		tooOld := h.limits.MaxAge > 0 && now.Sub(oldest.Written) > h.limits.MaxAge
+		if !overCount && !overBytes && !tooOld {
+			return
+		}
+
+		if err := os.Remove(filepath.Join(h.dir, oldest.File)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove history segment %s: %v", oldest.File, err)
+			return
+		}
+		records -= oldest.Records
+		bytes -= oldest.Bytes
+		h.segments = h.segments[1:]
+	}
+}
+
+// saveIndex writes the segment summaries atomically (temp file + rename). Caller must hold mu.
+func (h *JobHistory) saveIndex() {
+	data, err := json.Marshal(h.segments)
+	if err != nil {
+		return
+	}
+	path := filepath.Join(h.dir, historyIndexFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		log.Printf("Failed to save history index: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		log.Printf("Failed to save history index: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jetsetgo/local-print-server/internal/config"
+	"github.com/jetsetgo/local-print-server/internal/jobs"
+)
+
+var historyEpoch = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// fillHistory appends n completed jobs created a minute apart
+func fillHistory(t *testing.T, h *JobHistory, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		rec := JobRecord{
+			ID:        fmt.Sprintf("job-%03d", i),
+			PrinterID: "p1",
+			Status:    "completed",
+			CreatedAt: historyEpoch.Add(time.Duration(i) * time.Minute),
+		}
+		if err := h.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func openTestHistory(t *testing.T, dir string, limits HistoryLimits) *JobHistory {
+	t.Helper()
+	h, err := OpenJobHistory(dir, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.segmentMax = 1024 // A handful of records per segment
+	return h
+}
+
+func TestJobHistoryRotation(t *testing.T) {
+	dir := t.TempDir()
+	h := openTestHistory(t, dir, HistoryLimits{})
+	fillHistory(t, h, 100)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if len(files) < 5 {
+		t.Fatalf("got %d segments, want the history split over several", len(files))
+	}
+	if records, _ := h.Size(); records != 100 {
+		t.Errorf("Size = %d records, want 100", records)
+	}
+
+	// The index is rebuilt from the segments if it is lost
+	os.Remove(filepath.Join(dir, historyIndexFile))
+	h = openTestHistory(t, dir, HistoryLimits{})
+	if records, _ := h.Size(); records != 100 {
+		t.Errorf("after rebuilding the index Size = %d records, want 100", records)
+	}
+	recent, err := h.Recent(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 3 || recent[0].ID != "job-097" || recent[2].ID != "job-099" {
+		t.Errorf("Recent(3) = %v, want job-097..job-099 oldest first", ids(recent))
+	}
+}
+
+func TestJobHistoryRetention(t *testing.T) {
+	dir := t.TempDir()
+	h := openTestHistory(t, dir, HistoryLimits{MaxRecords: 30})
+	fillHistory(t, h, 100)
+
+	records, _ := h.Size()
+	if records < 30 || records > 45 {
+		t.Errorf("kept %d records, want 30 plus at most a segment", records)
+	}
+	if len(h.segments) == 0 || h.segments[0].MinCreated.Before(historyEpoch.Add(50*time.Minute)) {
+		t.Error("oldest segments were not dropped")
+	}
+}
+
+func TestJobHistoryFind(t *testing.T) {
+	h := openTestHistory(t, t.TempDir(), HistoryLimits{})
+	fillHistory(t, h, 100)
+
+	// Re-recorded job: its latest record wins
+	rec := JobRecord{ID: "job-098", PrinterID: "p1", Status: "failed", CreatedAt: historyEpoch.Add(98 * time.Minute)}
+	if err := h.Append(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	q := JobQuery{Limit: 5}
+	page, err := h.Find(q, 5, map[string]bool{"job-097": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"job-099", "job-098", "job-096", "job-095", "job-094"}
+	if fmt.Sprint(ids(page)) != fmt.Sprint(want) {
+		t.Errorf("Find = %v, want %v", ids(page), want)
+	}
+	if page[1].Status != "failed" {
+		t.Errorf("job-098 status = %q, want its latest record's", page[1].Status)
+	}
+
+	q = JobQuery{Statuses: []string{"completed"}, Limit: 2}
+	page, _ = h.Find(q, 2, nil)
+	if fmt.Sprint(ids(page)) != "[job-099 job-097]" {
+		t.Errorf("completed jobs = %v, want [job-099 job-097]", ids(page))
+	}
+
+	// Paging from a cursor and a time range
+	q = JobQuery{From: historyEpoch.Add(10 * time.Minute), To: historyEpoch.Add(20 * time.Minute), Limit: 100}
+	q.after = &jobCursor{createdAt: historyEpoch.Add(15 * time.Minute), id: "job-015"}
+	page, _ = h.Find(q, 100, nil)
+	if len(page) != 5 || page[0].ID != "job-014" || page[4].ID != "job-010" {
+		t.Errorf("range page = %v, want job-014..job-010", ids(page))
+	}
+}
+
+func ids(records []JobRecord) []string {
+	out := make([]string, len(records))
+	for i, r := range records {
+		out[i] = r.ID
+	}
+	return out
+}
+
+func TestRecordHistoryAfterEviction(t *testing.T) {
+	s := &Server{
+		config:     &config.Config{Printers: []config.PrinterConfig{{ID: "p1", Name: "Front"}}},
+		jobBuffer:  NewJobBuffer(2),
+		logBuffer:  NewLogBuffer(10),
+		jobHistory: openTestHistory(t, t.TempDir(), HistoryLimits{}),
+	}
+
+	// A job held behind a paused printer while newer jobs push it out of the list
+	held := jobs.Job{ID: "held", PrinterID: "p1", Data: []byte("abc"), CreatedAt: historyEpoch}
+	s.jobBuffer.Add(s.jobRecordFor(held, "queued", ""))
+	for i := 0; i < 3; i++ {
+		s.jobBuffer.Add(JobRecord{ID: fmt.Sprintf("newer-%d", i), CreatedAt: historyEpoch.Add(time.Minute)})
+	}
+	if _, ok := s.jobBuffer.Get("held"); ok {
+		t.Fatal("test setup: job not evicted")
+	}
+
+	held.CopiesPrinted = 1
+	s.handleJobStatus(held, "completed", "")
+
+	rec, ok := s.findJob("held")
+	if !ok {
+		t.Fatal("evicted job missing from history")
+	}
+	if rec.Status != "completed" || rec.PrinterName != "Front" || rec.DataSize != 3 || rec.CopiesPrinted != 1 || rec.CompletedAt == nil {
+		t.Errorf("history record = %+v", rec)
+	}
+}
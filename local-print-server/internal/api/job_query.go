@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page sizes for GET /api/jobs
+const (
+	defaultJobLimit = 50
+	maxJobLimit     = 500
+)
+
+// JobQuery filters and pages job records. Results are newest first.
+type JobQuery struct {
+	Statuses  []string  // Any of these statuses; empty matches all
+	PrinterID string    // Exact printer ID
+	From      time.Time // Created at or after
+	To        time.Time // Created before
+	Text      string    // Case-insensitive substring of the job ID
+	ID        string    // Exact job ID, for looking up a single job
+	Limit     int
+
+	// Position after which the page starts, from the previous page's next_cursor
+	after *jobCursor
+}
+
+// jobCursor is the sort position of the last record on a page
+type jobCursor struct {
+	createdAt time.Time
+	id        string
+}
+
+// parseJobQuery reads a JobQuery from GET /api/jobs parameters:
+// status (comma-separated), printer_id, from, to (RFC 3339), q, limit and cursor
+func parseJobQuery(v url.Values) (JobQuery, error) {
+	q := JobQuery{
+		PrinterID: v.Get("printer_id"),
+		Text:      strings.ToLower(strings.TrimSpace(v.Get("q"))),
+		Limit:     defaultJobLimit,
+	}
+	for _, status := range strings.Split(v.Get("status"), ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			q.Statuses = append(q.Statuses, status)
+		}
+	}
+
+	var err error
+	if s := v.Get("from"); s != "" {
+		if q.From, err = time.Parse(time.RFC3339, s); err != nil {
+			return q, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if s := v.Get("to"); s != "" {
+		if q.To, err = time.Parse(time.RFC3339, s); err != nil {
+			return q, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return q, fmt.Errorf("invalid limit: %q", s)
+		}
+		q.Limit = min(n, maxJobLimit)
+	}
+	if s := v.Get("cursor"); s != "" {
+		c, err := decodeJobCursor(s)
+		if err != nil {
+			return q, err
+		}
+		q.after = &c
+	}
+	return q, nil
+}
+
+// match reports whether a record passes the query's filters
+func (q JobQuery) match(r JobRecord) bool {
+	if len(q.Statuses) > 0 {
+		found := false
+		for _, s := range q.Statuses {
+			if r.Status == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.PrinterID != "" && r.PrinterID != q.PrinterID {
+		return false
+	}
+	if !q.From.IsZero() && r.CreatedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !r.CreatedAt.Before(q.To) {
+		return false
+	}
+	if q.ID != "" && r.ID != q.ID {
+		return false
+	}
+	if q.Text != "" && !strings.Contains(strings.ToLower(r.ID), q.Text) {
+		return false
+	}
+	return true
+}
+
+// run filters, sorts and pages records. Returns the page and the cursor for the
+// next one ("" on the last page).
+func (q JobQuery) run(records []JobRecord) ([]JobRecord, string) {
+	matched := make([]JobRecord, 0, len(records))
+	for _, r := range records {
+		if q.match(r) && (q.after == nil || q.after.before(r)) {
+			matched = append(matched, r)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return newerJob(matched[i], matched[j])
+	})
+
+	if len(matched) <= q.Limit {
+		return matched, ""
+	}
+	page := matched[:q.Limit]
+	last := page[len(page)-1]
+	return page, encodeJobCursor(jobCursor{createdAt: last.CreatedAt, id: last.ID})
+}
+
+// newerJob orders records newest first, by ID among jobs created at the same instant
+func newerJob(a, b JobRecord) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID > b.ID
+}
+
+// before reports whether r sorts after the cursor position, i.e. belongs on a later page
+func (c jobCursor) before(r JobRecord) bool {
+	return newerJob(JobRecord{ID: c.id, CreatedAt: c.createdAt}, r)
+}
+
+func encodeJobCursor(c jobCursor) string {
+	raw := strconv.FormatInt(c.createdAt.UnixNano(), 10) + ":" + c.id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeJobCursor(s string) (jobCursor, error) {
+	errInvalid := errors.New("invalid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return jobCursor{}, errInvalid
+	}
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return jobCursor{}, errInvalid
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return jobCursor{}, errInvalid
+	}
+	return jobCursor{createdAt: time.Unix(0, nanos), id: id}, nil
+}
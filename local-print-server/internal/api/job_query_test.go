@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestJobCursorRoundTrip(t *testing.T) {
+	for _, c := range []jobCursor{
+		{createdAt: time.Date(2025, 3, 1, 9, 30, 0, 123456789, time.UTC), id: "job-1"},
+		{createdAt: time.Unix(0, 0), id: "local_1740000000000_7"},
+		{createdAt: time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC), id: "with:colon"},
+	} {
+		got, err := decodeJobCursor(encodeJobCursor(c))
+		if err != nil {
+			t.Fatalf("decode %+v: %v", c, err)
+		}
+		if !got.createdAt.Equal(c.createdAt) || got.id != c.id {
+			t.Errorf("round trip of %+v gave %+v", c, got)
+		}
+	}
+
+	for _, s := range []string{"!!!", "bm9jb2xvbg", "eHl6OmpvYg"} { // Not base64, "nocolon", "xyz:job"
+		if _, err := decodeJobCursor(s); err == nil {
+			t.Errorf("cursor %q decoded", s)
+		}
+	}
+}
+
+func TestParseJobQuery(t *testing.T) {
+	v := url.Values{
+		"status":     {"failed, completed,"},
+		"printer_id": {"p1"},
+		"from":       {"2025-01-01T00:00:00Z"},
+		"q":          {" ABC "},
+		"limit":      {"10000"},
+	}
+	q, err := parseJobQuery(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Statuses) != 2 || q.Statuses[1] != "completed" || q.PrinterID != "p1" || q.Text != "abc" {
+		t.Errorf("query = %+v", q)
+	}
+	if q.Limit != maxJobLimit || q.From.IsZero() || !q.To.IsZero() {
+		t.Errorf("limit %d, from %v, to %v", q.Limit, q.From, q.To)
+	}
+
+	for _, bad := range []url.Values{
+		{"limit": {"0"}},
+		{"from": {"yesterday"}},
+		{"cursor": {"!!!"}},
+	} {
+		if _, err := parseJobQuery(bad); err == nil {
+			t.Errorf("parseJobQuery(%v) succeeded", bad)
+		}
+	}
+}
+
+func TestJobQueryPaging(t *testing.T) {
+	at := historyEpoch
+	records := []JobRecord{
+		{ID: "a", CreatedAt: at},
+		{ID: "b", CreatedAt: at}, // Same instant as a: ordered by ID
+		{ID: "c", CreatedAt: at.Add(time.Second)},
+		{ID: "d", CreatedAt: at.Add(-time.Second)},
+	}
+
+	var seen []string
+	q := JobQuery{Limit: 2}
+	for page := 0; page < 3; page++ {
+		got, next := q.run(records)
+		seen = append(seen, ids(got)...)
+		if next == "" {
+			break
+		}
+		c, err := decodeJobCursor(next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.after = &c
+	}
+	if len(seen) != 4 || seen[0] != "c" || seen[1] != "b" || seen[2] != "a" || seen[3] != "d" {
+		t.Errorf("pages = %v, want [c b a d]", seen)
+	}
+}
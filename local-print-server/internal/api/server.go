@@ -6,13 +6,21 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jetsetgo/local-print-server/internal/cloud"
 	"github.com/jetsetgo/local-print-server/internal/config"
+	"github.com/jetsetgo/local-print-server/internal/escpos"
+	"github.com/jetsetgo/local-print-server/internal/jobs"
+	"github.com/jetsetgo/local-print-server/internal/markup"
+	"github.com/jetsetgo/local-print-server/internal/preview"
 	"github.com/jetsetgo/local-print-server/internal/printer"
+	"github.com/jetsetgo/local-print-server/internal/receipt"
 )
 
 // Server represents the HTTP server
@@ -20,11 +28,17 @@ type Server struct {
 	config         *config.Config
 	configMu       sync.RWMutex
 	printerManager *printer.Manager
-	wsClient       *cloud.WSClient
-	pollClient     *cloud.PollClient
+	tracker        *printer.Tracker
+	spooler        *jobs.Spooler
+	outbox         *cloud.Outbox
+	executor       *cloud.Executor
+	transport      cloud.Transport // nil until registered; read with cloudTransport
+	transportMu    sync.RWMutex    // Guards transport
+	lifecycleMu    sync.Mutex      // Serialises starting, stopping and reconnecting it
 	mux            *http.ServeMux
 	logBuffer      *LogBuffer
 	jobBuffer      *JobBuffer
+	jobHistory     *JobHistory
 }
 
 // NewServer creates a new HTTP server
@@ -43,28 +57,158 @@ func NewServer(cfg *config.Config, logBuf *LogBuffer, jobBuf *JobBuffer) *Server
 	for _, p := range cfg.Printers {
 		switch p.Type {
 		case "network":
-			np := printer.NewNetworkPrinter(p.ID, p.Name, p.Address, p.Port)
-			s.printerManager.AddPrinter(np)
+			s.printerManager.AddPrinter(newNetworkPrinter(p))
 		case "usb":
 			// TODO: Add USB printer support
 		}
 	}
 
+	// Open the offline job queue; without it jobs are printed directly
+	queue, err := jobs.OpenQueue(filepath.Join(cfg.Jobs.DataDir, "queue"))
+	if err != nil {
+		logBuf.LogError("Offline job queue unavailable: %v", err)
+	}
+	seen, err := jobs.OpenSeenIndex(filepath.Join(cfg.Jobs.DataDir, "seen.jsonl"), cfg.Jobs.DedupeTTL)
+	if err != nil {
+		logBuf.LogError("Seen-job index unavailable, duplicate jobs will not be suppressed: %v", err)
+	}
+	s.spooler = jobs.NewSpooler(queue, seen, printerMgr)
+	s.spooler.OnStatus = s.handleJobStatus
+	s.spooler.Policy = s.retryPolicy
+	s.spooler.OnCopyPrinted = func(job jobs.Job) {
+		s.jobBuffer.UpdateCopies(job.ID, job.CopiesPrinted)
+	}
+	s.spooler.OnProgress = func(job jobs.Job, sent, total int) {
+		s.jobBuffer.UpdateProgress(job.ID, sent, total)
+	}
+	s.spooler.OnOverdue = func(job jobs.Job, age time.Duration) {
+		s.logBuffer.LogWarn("Job %s has been queued for %s (printer %s: %s)", job.ID, age.Round(time.Minute), job.PrinterID, job.LastError)
+	}
+
+	// Job status updates wait here until the cloud has them
+	outbox, err := cloud.OpenOutbox(filepath.Join(cfg.Jobs.DataDir, "outbox.jsonl"))
+	if err != nil {
+		logBuf.LogError("Status outbox unavailable, status updates may be lost while offline: %v", err)
+	} else {
+		s.outbox = outbox
+		s.outbox.Deliver = s.deliverStatus
+		if n := s.outbox.Len(); n > 0 {
+			logBuf.LogInfo("%d undelivered job status update(s) will be sent to the cloud", n)
+		}
+	}
+
+	// Finished jobs are also kept on disk for searching, and to show recent jobs after a restart
+	history, err := OpenJobHistory(filepath.Join(cfg.Jobs.DataDir, "history"), HistoryLimits{
+		MaxRecords: cfg.Jobs.HistorySize,
+		MaxAge:     cfg.Jobs.HistoryMaxAge,
+		MaxBytes:   cfg.Jobs.HistoryMaxBytes,
+	})
+	if err != nil {
+		logBuf.LogError("Job history unavailable, only recent jobs can be searched: %v", err)
+	} else {
+		s.jobHistory = history
+		recent, err := history.Recent(s.jobBuffer.cap)
+		if err != nil {
+			logBuf.LogWarn("Failed to load recent jobs from history: %v", err)
+		}
+		for _, rec := range recent {
+			s.jobBuffer.Add(rec)
+		}
+	}
+
+	s.jobBuffer.SetPayloadLimits(cfg.Jobs.ReprintMaxBytes, cfg.Jobs.ReprintRetention)
+
+	for _, p := range cfg.Printers {
+		if p.Paused {
+			s.spooler.Pause(p.ID)
+		}
+	}
+
+	// Show jobs left in the queue by a previous run
+	for _, job := range s.spooler.Pending() {
+		status := "queued"
+		if job.NotBefore.After(time.Now()) {
+			status = "scheduled"
+		}
+		s.jobBuffer.Add(s.jobRecordFor(job, status, job.LastError))
+	}
+	if n := len(s.spooler.Pending()); n > 0 {
+		logBuf.LogInfo("%d queued job(s) will be replayed", n)
+	}
+
+	// Jobs from the cloud, over whichever transport, are queued by the executor
+	s.executor = cloud.NewExecutor(s.spooler)
+	s.executor.Report = s.reportStatus
+	s.executor.RenderTemplate = s.renderTemplate
+	s.executor.OnPausePrinter = s.setPrinterPaused
+	s.executor.OnJobReceived = s.handleCloudJobReceived
+	s.executor.OnJobCompleted = func(jobID, status, errMsg string) {
+		s.jobBuffer.UpdateStatus(jobID, status, errMsg)
+	}
+
 	// Create cloud client if configured
 	if cfg.Cloud.ServerID != "" && cfg.Cloud.APIKey != "" {
-		if cfg.Cloud.UseWebSocket {
-			s.wsClient = cloud.NewWSClient(&cfg.Cloud, printerMgr)
-			s.configureWSClient(s.wsClient)
-		} else {
-			s.pollClient = cloud.NewPollClient(&cfg.Cloud, printerMgr)
-			s.configurePollClient(s.pollClient)
-		}
+		s.transport = s.newTransport()
 	}
 
+	// Report printer status changes to the cloud as they happen
+	printerMgr.OnStatusChange = s.handlePrinterStatusChange
+
+	// Follow pinned printers across address changes
+	s.tracker = printer.NewTracker(printerMgr, cfg.Server.ResolveInterval)
+	s.tracker.OnUpdated = s.handlePrinterRelocated
+
 	s.setupRoutes()
 	return s
 }
 
+// newNetworkPrinter creates a network printer from its configuration
+func newNetworkPrinter(pc config.PrinterConfig) *printer.NetworkPrinter {
+	np := printer.NewNetworkPrinter(pc.ID, pc.Name, pc.Address, pc.Port)
+	applyNetworkSettings(np, pc)
+	return np
+}
+
+// applyNetworkSettings sets everything about a network printer that can change without
+// replacing it
+func applyNetworkSettings(np *printer.NetworkPrinter, pc config.PrinterConfig) {
+	np.SetName(pc.Name)
+	np.SetPin(printer.Pin{
+		By:         pc.PinBy,
+		MAC:        pc.MAC,
+		Serial:     pc.Serial,
+		SNMPSerial: pc.SNMPSerial,
+	})
+	np.SetWriteOptions(printer.WriteOptions{
+		ChunkSize:    pc.ChunkSize,
+		ChunkTimeout: pc.ChunkTimeout,
+		BytesPerSec:  pc.MaxBytesPerSec,
+	})
+}
+
+// updateNetworkPrinter applies a network printer's new configuration. The printer is
+// only replaced when its address or port changed, so settings changes keep its cached
+// GS I identification.
+func (s *Server) updateNetworkPrinter(old, pc config.PrinterConfig) {
+	if existing, err := s.printerManager.GetPrinter(pc.ID); err == nil && old.Address == pc.Address && old.Port == pc.Port {
+		if np, ok := existing.(*printer.NetworkPrinter); ok {
+			applyNetworkSettings(np, pc)
+			return
+		}
+	}
+	s.printerManager.RemovePrinter(pc.ID)
+	s.printerManager.AddPrinter(newNetworkPrinter(pc))
+}
+
+// validatePinBy checks a printer's pin_by setting
+func validatePinBy(by string) error {
+	if !printer.ValidPinBy(by) {
+		return fmt.Errorf("unknown pin_by %q (use %s, %s, %s or %s)", by,
+			printer.PinByAddress, printer.PinByMAC, printer.PinBySerial, printer.PinBySNMPSerial)
+	}
+	return nil
+}
+
 // setupRoutes configures the HTTP routes
 func (s *Server) setupRoutes() {
 	// Health check
@@ -84,10 +228,19 @@ func (s *Server) setupRoutes() {
 	s.mux.HandleFunc("DELETE /api/printers/{id}", s.handleDeletePrinter)
 	s.mux.HandleFunc("POST /api/printers/discover", s.handleDiscoverPrinters)
 	s.mux.HandleFunc("POST /api/printers/{id}/test", s.handleTestPrint)
+	s.mux.HandleFunc("GET /api/printers/{id}/info", s.handlePrinterInfo)
+	s.mux.HandleFunc("GET /api/printers/{id}/history", s.handlePrinterHistory)
+	s.mux.HandleFunc("POST /api/printers/{id}/pause", s.handlePausePrinter)
+	s.mux.HandleFunc("POST /api/printers/{id}/resume", s.handleResumePrinter)
 
 	// Print jobs
 	s.mux.HandleFunc("POST /api/print", s.handlePrint)
+	s.mux.HandleFunc("POST /api/print/text", s.handlePrintText)
+	s.mux.HandleFunc("POST /api/print/receipt", s.handlePrintReceipt)
 	s.mux.HandleFunc("GET /api/jobs", s.handleGetJobs)
+	s.mux.HandleFunc("DELETE /api/jobs/{id}", s.handleCancelJob)
+	s.mux.HandleFunc("POST /api/jobs/{id}/reprint", s.handleReprintJob)
+	s.mux.HandleFunc("GET /api/jobs/{id}/preview", s.handlePreviewJob)
 
 	// Logs
 	s.mux.HandleFunc("GET /api/logs", s.handleGetLogs)
@@ -104,6 +257,14 @@ func (s *Server) setupRoutes() {
 
 // Start starts the HTTP server and cloud connection
 func (s *Server) Start() error {
+	// Jobs and status updates must have somewhere to go before the cloud sends any
+	s.spooler.Start(s.config.Jobs.ReplayInterval, s.config.Jobs.QueueRetention, s.config.Jobs.ExpireAfter)
+	if s.outbox != nil {
+		s.outbox.Start()
+	}
+	s.printerManager.StartHealthMonitor(s.config.Server.HealthInterval)
+	s.tracker.Start()
+
 	// Start cloud client
 	s.startCloudClient()
 
@@ -121,34 +282,58 @@ func (s *Server) startCloudClient() {
 		return
 	}
 
-	if s.wsClient != nil {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+
+	t := s.cloudTransport()
+	if t == nil {
+		return
+	}
+	if s.config.Cloud.UseWebSocket {
 		s.logBuffer.LogInfo("Starting WebSocket connection to cloud...")
-		s.wsClient.Start()
-	} else if s.pollClient != nil {
+	} else {
 		s.logBuffer.LogInfo("Starting HTTP polling (interval: %s)...", s.config.Cloud.PollInterval)
-		s.pollClient.Start()
 	}
+	t.Start()
 }
 
-// stopCloudClients stops all cloud clients
+// stopCloudClients stops the cloud transport
 func (s *Server) stopCloudClients() {
-	if s.wsClient != nil {
-		s.wsClient.Stop()
-		s.wsClient = nil
-	}
-	if s.pollClient != nil {
-		s.pollClient.Stop()
-		s.pollClient = nil
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	s.stopTransport()
+}
+
+// stopTransport stops and clears the transport. Caller must hold lifecycleMu.
+func (s *Server) stopTransport() {
+	s.transportMu.Lock()
+	t := s.transport
+	s.transport = nil
+	s.transportMu.Unlock()
+
+	if t != nil {
+		t.Stop()
 	}
 }
 
+// cloudTransport returns the cloud transport, or nil if there is none
+func (s *Server) cloudTransport() cloud.Transport {
+	s.transportMu.RLock()
+	defer s.transportMu.RUnlock()
+	return s.transport
+}
+
 // reconnectCloudClient stops any running cloud client and starts a fresh one from current config
 func (s *Server) reconnectCloudClient() {
-	s.stopCloudClients()
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+
+	s.stopTransport()
 
 	s.configMu.RLock()
 	hasCredentials := s.config.Cloud.ServerID != "" && s.config.Cloud.APIKey != ""
 	useWs := s.config.Cloud.UseWebSocket
+	pollInterval := s.config.Cloud.PollInterval
 	s.configMu.RUnlock()
 
 	if !hasCredentials {
@@ -156,16 +341,15 @@ func (s *Server) reconnectCloudClient() {
 		return
 	}
 
+	t := s.newTransport()
+	s.transportMu.Lock()
+	s.transport = t
+	s.transportMu.Unlock()
+	t.Start()
 	if useWs {
-		s.wsClient = cloud.NewWSClient(&s.config.Cloud, s.printerManager)
-		s.configureWSClient(s.wsClient)
-		s.wsClient.Start()
 		s.logBuffer.LogInfo("WebSocket client reconnected")
 	} else {
-		s.pollClient = cloud.NewPollClient(&s.config.Cloud, s.printerManager)
-		s.configurePollClient(s.pollClient)
-		s.pollClient.Start()
-		s.logBuffer.LogInfo("Polling client reconnected (interval: %s)", s.config.Cloud.PollInterval)
+		s.logBuffer.LogInfo("Polling client reconnected (interval: %s)", pollInterval)
 	}
 }
 
@@ -179,6 +363,12 @@ func (s *Server) handleReconnect(w http.ResponseWriter, r *http.Request) {
 // Stop gracefully stops the server
 func (s *Server) Stop() {
 	s.stopCloudClients()
+	s.tracker.Stop()
+	s.printerManager.StopHealthMonitor()
+	s.spooler.Stop()
+	if s.outbox != nil {
+		s.outbox.Stop()
+	}
 }
 
 // handleHealth handles the health check endpoint
@@ -360,18 +550,19 @@ func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 			"host": s.config.Server.Host,
 		},
 		"cloud": map[string]interface{}{
-			"endpoint":             s.config.Cloud.Endpoint,
-			"ws_endpoint":          s.config.Cloud.WSEndpoint,
-			"server_id":            s.config.Cloud.ServerID,
-			"tenant":               s.config.Cloud.Tenant,
-			"server_name":          s.config.Cloud.ServerName,
-			"location":             s.config.Cloud.Location,
-			"api_key_prefix":       apiKeyPrefix,
-			"use_websocket":        s.config.Cloud.UseWebSocket,
-			"poll_interval":        s.config.Cloud.PollInterval.String(),
-			"ws_reconnect_delay":   s.config.Cloud.WSReconnectDelay.String(),
+			"endpoint":               s.config.Cloud.Endpoint,
+			"ws_endpoint":            s.config.Cloud.WSEndpoint,
+			"server_id":              s.config.Cloud.ServerID,
+			"tenant":                 s.config.Cloud.Tenant,
+			"server_name":            s.config.Cloud.ServerName,
+			"location":               s.config.Cloud.Location,
+			"api_key_prefix":         apiKeyPrefix,
+			"use_websocket":          s.config.Cloud.UseWebSocket,
+			"poll_interval":          s.config.Cloud.PollInterval.String(),
+			"ws_reconnect_delay":     s.config.Cloud.WSReconnectDelay.String(),
 			"ws_max_reconnect_delay": s.config.Cloud.WSMaxReconnect.String(),
-			"ws_ping_interval":     s.config.Cloud.WSPingInterval.String(),
+			"ws_ping_interval":       s.config.Cloud.WSPingInterval.String(),
+			"ws_fallback_after":      s.config.Cloud.WSFallbackAfter,
 		},
 		"printers":      printers,
 		"config_path":   s.config.ConfigPath,
@@ -448,6 +639,9 @@ func (s *Server) handleUpdateConfig(w http.ResponseWriter, r *http.Request) {
 				s.config.Cloud.WSPingInterval = d
 			}
 		}
+		if v, ok := cloudCfg["ws_fallback_after"].(float64); ok && v >= 0 {
+			s.config.Cloud.WSFallbackAfter = int(v)
+		}
 	}
 
 	// Handle re-register (clear credentials)
@@ -490,18 +684,39 @@ func (s *Server) handleListPrinters(w http.ResponseWriter, r *http.Request) {
 
 	printers := make([]map[string]interface{}, 0)
 	for _, p := range s.config.Printers {
-		status := "unknown"
-		// Check status from printer manager
-		if mgdPrinter, err := s.printerManager.GetPrinter(p.ID); err == nil {
-			status = mgdPrinter.Status()
-		}
+		// Cached by the health monitor, so this never blocks on a dial
+		health := s.printerManager.Health(p.ID)
 		pm := map[string]interface{}{
 			"id": p.ID, "name": p.Name, "type": p.Type,
-			"status": status, "paper_width": p.PaperWidth,
+			"status": s.printerStatus(p.ID, health), "paper_width": p.PaperWidth,
+			"queue_depth": s.spooler.Depth(p.ID), "paused": p.Paused,
+			"profile": p.Profile, "code_page": p.CodePage,
+		}
+		if !health.CheckedAt.IsZero() {
+			pm["status_checked_at"] = health.CheckedAt
 		}
 		if p.Type == "network" {
 			pm["address"] = p.Address
 			pm["port"] = p.Port
+			pm["pin_by"] = p.PinBy
+			pm["mac"] = p.MAC
+			pm["serial"] = p.Serial
+			pm["snmp_serial"] = p.SNMPSerial
+			if p.ChunkSize > 0 {
+				pm["chunk_size"] = p.ChunkSize
+			}
+			if p.ChunkTimeout > 0 {
+				pm["chunk_timeout"] = p.ChunkTimeout.String()
+			}
+			if p.MaxBytesPerSec > 0 {
+				pm["max_bytes_per_sec"] = p.MaxBytesPerSec
+			}
+		}
+		if info := s.cachedPrinterInfo(p.ID); info != nil {
+			pm["info"] = info
+		}
+		if p.Retry != nil {
+			pm["retry"] = retryConfigJSON(*p.Retry)
 		}
 		printers = append(printers, pm)
 	}
@@ -526,6 +741,18 @@ func (s *Server) handleAddPrinter(w http.ResponseWriter, r *http.Request) {
 	if p.PaperWidth == 0 {
 		p.PaperWidth = 80
 	}
+	if err := validateTextSettings(p.Profile, p.CodePage); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+	if err := validatePinBy(p.PinBy); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+	if err := validateWriteSettings(p); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
 
 	s.configMu.Lock()
 	// Check for duplicate ID
@@ -541,13 +768,14 @@ func (s *Server) handleAddPrinter(w http.ResponseWriter, r *http.Request) {
 
 	// Add to printer manager
 	if p.Type == "network" {
-		np := printer.NewNetworkPrinter(p.ID, p.Name, p.Address, p.Port)
-		s.printerManager.AddPrinter(np)
+		s.printerManager.AddPrinter(newNetworkPrinter(p))
 	}
 
 	// Save config
 	if s.config.ConfigPath != "" {
-		s.config.Save(s.config.ConfigPath)
+		if err := s.config.Save(s.config.ConfigPath); err != nil {
+			s.logBuffer.LogError("Failed to save config: %v", err)
+		}
 	}
 	s.configMu.Unlock()
 
@@ -576,29 +804,16 @@ func (s *Server) handleUpdatePrinter(w http.ResponseWriter, r *http.Request) {
 	found := false
 	for i, p := range s.config.Printers {
 		if p.ID == printerID {
-			if v, ok := updates["name"].(string); ok {
-				s.config.Printers[i].Name = v
-			}
-			if v, ok := updates["address"].(string); ok {
-				s.config.Printers[i].Address = v
-			}
-			if v, ok := updates["port"].(float64); ok {
-				s.config.Printers[i].Port = int(v)
-			}
-			if v, ok := updates["paper_width"].(float64); ok {
-				s.config.Printers[i].PaperWidth = int(v)
+			updated, err := applyPrinterUpdates(p, updates)
+			if err != nil {
+				s.configMu.Unlock()
+				json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+				return
 			}
+			s.config.Printers[i] = updated
 
-			// Recreate printer in manager if network settings changed
 			if p.Type == "network" {
-				s.printerManager.RemovePrinter(p.ID)
-				np := printer.NewNetworkPrinter(
-					s.config.Printers[i].ID,
-					s.config.Printers[i].Name,
-					s.config.Printers[i].Address,
-					s.config.Printers[i].Port,
-				)
-				s.printerManager.AddPrinter(np)
+				s.updateNetworkPrinter(p, updated)
 			}
 
 			found = true
@@ -613,7 +828,9 @@ func (s *Server) handleUpdatePrinter(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if s.config.ConfigPath != "" {
-		s.config.Save(s.config.ConfigPath)
+		if err := s.config.Save(s.config.ConfigPath); err != nil {
+			s.logBuffer.LogError("Failed to save config: %v", err)
+		}
 	}
 	s.configMu.Unlock()
 
@@ -622,6 +839,163 @@ func (s *Server) handleUpdatePrinter(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
 }
 
+// applyPrinterUpdates returns pc with the fields of a PUT /api/printers/{id} body applied.
+// Every field is checked before the caller changes anything, so a rejected update
+// leaves the printer as it was.
+func applyPrinterUpdates(pc config.PrinterConfig, updates map[string]interface{}) (config.PrinterConfig, error) {
+	if v, ok := updates["name"].(string); ok {
+		pc.Name = v
+	}
+	if v, ok := updates["address"].(string); ok {
+		pc.Address = v
+	}
+	if v, ok := updates["port"].(float64); ok {
+		pc.Port = int(v)
+	}
+	if v, ok := updates["paper_width"].(float64); ok {
+		pc.PaperWidth = int(v)
+	}
+	if v, ok := updates["pin_by"].(string); ok {
+		if err := validatePinBy(v); err != nil {
+			return pc, err
+		}
+		pc.PinBy = v
+	}
+	if v, ok := updates["mac"].(string); ok {
+		pc.MAC = printer.NormalizeMAC(v)
+	}
+	if v, ok := updates["serial"].(string); ok {
+		pc.Serial = v
+	}
+	if v, ok := updates["snmp_serial"].(string); ok {
+		pc.SNMPSerial = v
+	}
+	if v, ok := updates["retry"]; ok {
+		rc, err := parseRetryConfig(v)
+		if err != nil {
+			return pc, err
+		}
+		pc.Retry = rc
+	}
+	if v, ok := updates["chunk_size"].(float64); ok {
+		pc.ChunkSize = int(v)
+	}
+	if v, ok := updates["chunk_timeout"].(string); ok {
+		d, err := time.ParseDuration(v)
+		if v == "" {
+			d, err = 0, nil
+		}
+		if err != nil {
+			return pc, fmt.Errorf("invalid chunk_timeout: %w", err)
+		}
+		pc.ChunkTimeout = d
+	}
+	if v, ok := updates["max_bytes_per_sec"].(float64); ok {
+		pc.MaxBytesPerSec = int(v)
+	}
+	if v, ok := updates["profile"].(string); ok {
+		pc.Profile = v
+	}
+	if v, ok := updates["code_page"].(string); ok {
+		pc.CodePage = v
+	}
+	if err := validateTextSettings(pc.Profile, pc.CodePage); err != nil {
+		return pc, err
+	}
+	if err := validateWriteSettings(pc); err != nil {
+		return pc, err
+	}
+	return pc, nil
+}
+
+// validateWriteSettings checks a printer's chunking and throttle settings; 0 means the default
+func validateWriteSettings(pc config.PrinterConfig) error {
+	switch {
+	case pc.ChunkSize < 0:
+		return fmt.Errorf("chunk_size must not be negative")
+	case pc.ChunkTimeout < 0:
+		return fmt.Errorf("chunk_timeout must not be negative")
+	case pc.MaxBytesPerSec < 0:
+		return fmt.Errorf("max_bytes_per_sec must not be negative")
+	}
+	return nil
+}
+
+// validateTextSettings checks a printer's profile and code page
+func validateTextSettings(profile, codePage string) error {
+	prof, ok := escpos.LookupProfile(profile)
+	if !ok {
+		return fmt.Errorf("unknown profile %q (use %s)", profile, strings.Join(escpos.ProfileNames(), ", "))
+	}
+	if codePage == "" {
+		return nil
+	}
+	if _, ok := prof.CodePages[codePage]; !ok || !escpos.ValidCodePage(codePage) {
+		return fmt.Errorf("code page %q is not supported by the %s profile", codePage, prof.Name)
+	}
+	return nil
+}
+
+// parseRetryConfig parses a retry override from a printer update. Durations are
+// strings such as "2s"; null removes the override.
+func parseRetryConfig(v interface{}) (*config.RetryConfig, error) {
+	if v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("retry must be an object")
+	}
+
+	rc := &config.RetryConfig{}
+	if n, ok := m["max_attempts"].(float64); ok {
+		rc.MaxAttempts = int(n)
+	}
+	for key, dst := range map[string]*time.Duration{
+		"initial_backoff": &rc.InitialBackoff,
+		"max_backoff":     &rc.MaxBackoff,
+	} {
+		str, ok := m[key].(string)
+		if !ok || str == "" {
+			continue
+		}
+		d, err := time.ParseDuration(str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", key, err)
+		}
+		*dst = d
+	}
+	if list, ok := m["retry_on"].([]interface{}); ok {
+		rc.RetryOn = []string{}
+		for _, item := range list {
+			class, _ := item.(string)
+			if class != jobs.RetryOnConnect && class != jobs.RetryOnWrite {
+				return nil, fmt.Errorf("invalid retry_on value %q (use %q or %q)", class, jobs.RetryOnConnect, jobs.RetryOnWrite)
+			}
+			rc.RetryOn = append(rc.RetryOn, class)
+		}
+	}
+	return rc, nil
+}
+
+// retryConfigJSON formats a retry override for the API, with durations as strings
+func retryConfigJSON(rc config.RetryConfig) map[string]interface{} {
+	m := map[string]interface{}{}
+	if rc.MaxAttempts > 0 {
+		m["max_attempts"] = rc.MaxAttempts
+	}
+	if rc.InitialBackoff > 0 {
+		m["initial_backoff"] = rc.InitialBackoff.String()
+	}
+	if rc.MaxBackoff > 0 {
+		m["max_backoff"] = rc.MaxBackoff.String()
+	}
+	if rc.RetryOn != nil {
+		m["retry_on"] = rc.RetryOn
+	}
+	return m
+}
+
 func (s *Server) handleDeletePrinter(w http.ResponseWriter, r *http.Request) {
 	printerID := r.PathValue("id")
 	w.Header().Set("Content-Type", "application/json")
@@ -644,7 +1018,9 @@ func (s *Server) handleDeletePrinter(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if s.config.ConfigPath != "" {
-		s.config.Save(s.config.ConfigPath)
+		if err := s.config.Save(s.config.ConfigPath); err != nil {
+			s.logBuffer.LogError("Failed to save config: %v", err)
+		}
 	}
 	s.configMu.Unlock()
 
@@ -673,8 +1049,13 @@ func (s *Server) handleTestPrint(w http.ResponseWriter, r *http.Request) {
 	printerID := r.PathValue("id")
 	w.Header().Set("Content-Type", "application/json")
 
+	profile, _ := escpos.LookupProfile(escpos.DefaultProfile)
+	if opts, err := s.markupOptions(printerID); err == nil {
+		profile = opts.Profile
+	}
+
 	s.logBuffer.LogInfo("Sending test print to %s...", printerID)
-	err := s.printerManager.TestPrint(printerID)
+	err := s.printerManager.TestPrint(printerID, profile)
 	if err != nil {
 		s.logBuffer.LogError("Test print failed for %s: %v", printerID, err)
 		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
@@ -685,12 +1066,122 @@ func (s *Server) handleTestPrint(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "message": "Test print sent successfully"})
 }
 
+// handlePrinterInfo returns the printer's GS I identification.
+// The cached result is used unless ?refresh=true is given or nothing is cached yet.
+func (s *Server) handlePrinterInfo(w http.ResponseWriter, r *http.Request) {
+	printerID := r.PathValue("id")
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Query().Get("refresh") != "true" {
+		if info := s.cachedPrinterInfo(printerID); info != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "info": info})
+			return
+		}
+	}
+
+	info, err := s.printerManager.Identify(printerID)
+	if err != nil {
+		s.logBuffer.LogWarn("Printer identification failed for %s: %v", printerID, err)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+
+	s.logBuffer.LogInfo("Printer %s identified: %s %s (S/N %s)", printerID, info.Manufacturer, info.ModelName, info.Serial)
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "info": info})
+}
+
+// handlePrinterHistory returns a printer's status transitions and uptime statistics
+func (s *Server) handlePrinterHistory(w http.ResponseWriter, r *http.Request) {
+	printerID := r.PathValue("id")
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, err := s.printerManager.GetPrinter(printerID); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "Printer not found"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"printer_id":  printerID,
+		"current":     s.printerManager.Health(printerID),
+		"transitions": s.printerManager.StatusHistory(printerID),
+		"stats": map[string]interface{}{
+			"24h": s.printerManager.Uptime(printerID, "24h", 24*time.Hour),
+			"7d":  s.printerManager.Uptime(printerID, "7d", 7*24*time.Hour),
+		},
+	})
+}
+
+// handlePrinterRelocated persists a printer's new address or learned pin
+func (s *Server) handlePrinterRelocated(printerID, address string, pin printer.Pin) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	for i, p := range s.config.Printers {
+		if p.ID != printerID {
+			continue
+		}
+		if p.Address != address {
+			s.logBuffer.LogWarn("Printer %s changed address: %s -> %s", p.Name, p.Address, address)
+		}
+		s.config.Printers[i].Address = address
+		s.config.Printers[i].MAC = pin.MAC
+		s.config.Printers[i].Serial = pin.Serial
+		s.config.Printers[i].SNMPSerial = pin.SNMPSerial
+
+		if s.config.ConfigPath != "" {
+			if err := s.config.Save(s.config.ConfigPath); err != nil {
+				s.logBuffer.LogError("Failed to save config: %v", err)
+			}
+		}
+		return
+	}
+}
+
+// cachedPrinterInfo returns the cached identification for a printer, if any
+func (s *Server) cachedPrinterInfo(printerID string) *printer.PrinterInfo {
+	p, err := s.printerManager.GetPrinter(printerID)
+	if err != nil {
+		return nil
+	}
+	if idp, ok := p.(printer.Identifier); ok {
+		return idp.Info()
+	}
+	return nil
+}
+
+func (s *Server) handlePausePrinter(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.setPrinterPaused(r.PathValue("id"), true); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "paused": true})
+}
+
+func (s *Server) handleResumePrinter(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.setPrinterPaused(r.PathValue("id"), false); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "paused": false})
+}
+
 // --- Print ---
 
 // PrintRequest represents a print job request
 type PrintRequest struct {
 	PrinterID string `json:"printer_id"`
 	Data      []byte `json:"data"`
+	Priority  int    `json:"priority,omitempty"`
+
+	// Optional: copies, cut, feed, beep, open_drawer
+	Options map[string]interface{} `json:"options,omitempty"`
+
+	// Optional schedule (RFC 3339); the job is held until then
+	PrintAt   string `json:"print_at,omitempty"`
+	NotBefore string `json:"not_before,omitempty"`
 }
 
 func (s *Server) handlePrint(w http.ResponseWriter, r *http.Request) {
@@ -702,43 +1193,451 @@ func (s *Server) handlePrint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Record job
-	job := JobRecord{
-		ID:        fmt.Sprintf("local_%d", time.Now().UnixMilli()),
+	notBefore, err := jobs.ParseSchedule(req.PrintAt, req.NotBefore)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+
+	s.printLocal(w, JobRecord{PrinterID: req.PrinterID}, jobs.Job{
 		PrinterID: req.PrinterID,
-		Status:    "printing",
-		DataSize:  len(req.Data),
-		CreatedAt: time.Now(),
+		Data:      req.Data,
+		Priority:  req.Priority,
+		Options:   jobs.ParseOptions(req.Options),
+		NotBefore: notBefore,
+	})
+}
+
+// PrintTextRequest is a print job given as text with markup (see package markup)
+type PrintTextRequest struct {
+	PrinterID string                 `json:"printer_id"`
+	Text      string                 `json:"text"`
+	Priority  int                    `json:"priority,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+	PrintAt   string                 `json:"print_at,omitempty"`
+	NotBefore string                 `json:"not_before,omitempty"`
+}
+
+// handlePrintText converts text with markup to ESC/POS for the printer's profile, code
+// page and paper width, and prints it like POST /api/print
+func (s *Server) handlePrintText(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req PrintTextRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "Invalid request body"})
+		return
+	}
+	if req.Text == "" {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "text is required"})
+		return
 	}
 
-	// Find printer name
+	opts, err := s.markupOptions(req.PrinterID)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+	data, err := markup.Render(req.Text, opts)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+
+	notBefore, err := jobs.ParseSchedule(req.PrintAt, req.NotBefore)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+
+	s.printLocal(w, JobRecord{PrinterID: req.PrinterID}, jobs.Job{
+		PrinterID: req.PrinterID,
+		Data:      data,
+		Priority:  req.Priority,
+		Options:   jobs.ParseOptions(req.Options),
+		NotBefore: notBefore,
+	})
+}
+
+// markupOptions describes a configured printer for rendering markup
+func (s *Server) markupOptions(printerID string) (markup.Options, error) {
 	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+
 	for _, p := range s.config.Printers {
-		if p.ID == req.PrinterID {
-			job.PrinterName = p.Name
-			break
+		if p.ID != printerID {
+			continue
 		}
+		profile, ok := escpos.LookupProfile(p.Profile)
+		if !ok {
+			return markup.Options{}, fmt.Errorf("printer %s has unknown profile %q", p.ID, p.Profile)
+		}
+		return markup.Options{Profile: profile, CodePage: p.CodePage, Columns: paperColumns(p.PaperWidth)}, nil
+	}
+	return markup.Options{}, fmt.Errorf("printer not found: %s", printerID)
+}
+
+// paperColumns returns the Font A characters per line for a paper width in mm
+func paperColumns(paperWidth int) int {
+	if paperWidth == 58 {
+		return 32
+	}
+	return 48
+}
+
+// PrintReceiptRequest is a print job given as a template and its data, in the cloud's
+// JSON receipt format
+type PrintReceiptRequest struct {
+	PrinterID string                 `json:"printer_id"`
+	Template  string                 `json:"template,omitempty"` // Default "receipt"
+	Data      json.RawMessage        `json:"data"`
+	Priority  int                    `json:"priority,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"` // Job options, and paper_width to override the printer's
+	PrintAt   string                 `json:"print_at,omitempty"`
+	NotBefore string                 `json:"not_before,omitempty"`
+}
+
+// handlePrintReceipt renders a receipt template locally and prints it like POST /api/print
+func (s *Server) handlePrintReceipt(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req PrintReceiptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "Invalid request body"})
+		return
 	}
-	s.configMu.RUnlock()
 
-	s.jobBuffer.Add(job)
+	data, err := s.renderTemplate(req.PrinterID, req.Template, req.Data, req.Options)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
 
-	err := s.printerManager.Print(req.PrinterID, req.Data)
+	notBefore, err := jobs.ParseSchedule(req.PrintAt, req.NotBefore)
 	if err != nil {
-		s.jobBuffer.UpdateStatus(job.ID, "failed", err.Error())
 		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
 		return
 	}
 
-	s.jobBuffer.UpdateStatus(job.ID, "completed", "")
-	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+	s.printLocal(w, JobRecord{PrinterID: req.PrinterID}, jobs.Job{
+		PrinterID: req.PrinterID,
+		Data:      data,
+		Priority:  req.Priority,
+		Options:   jobs.ParseOptions(req.Options),
+		NotBefore: notBefore,
+	})
+}
+
+// renderTemplate renders a template job for a printer. options["paper_width"] lays it out
+// for other paper than the printer's.
+func (s *Server) renderTemplate(printerID, template string, data json.RawMessage, options map[string]interface{}) ([]byte, error) {
+	opts, err := s.markupOptions(printerID)
+	if err != nil {
+		return nil, err
+	}
+	if width, ok := options["paper_width"].(float64); ok {
+		opts.Columns = paperColumns(int(width))
+	}
+	return receipt.Render(template, data, opts)
+}
+
+// localJobSeq numbers local jobs so two in the same millisecond get different IDs
+var localJobSeq atomic.Uint64
+
+// newLocalJobID returns an ID for a locally submitted job. The timestamp keeps IDs
+// unique across restarts, which reset the counter.
+func newLocalJobID() string {
+	return fmt.Sprintf("local_%d_%d", time.Now().UnixMilli(), localJobSeq.Add(1))
+}
+
+// printLocal records and queues a locally submitted job, then writes the print result.
+// Callers fill in the printer and any extra record fields; IDs and timestamps are set here.
+func (s *Server) printLocal(w http.ResponseWriter, record JobRecord, job jobs.Job) {
+	record.ID = newLocalJobID()
+	record.PrinterName = s.printerName(record.PrinterID)
+	record.Status = "queued"
+	record.DataSize = len(job.Data)
+	record.Copies = job.Options.CopyCount()
+	record.CreatedAt = time.Now()
+	s.jobBuffer.Add(record)
+
+	job.ID = record.ID
+	job.Source = jobs.SourceLocal
+	job.CreatedAt = record.CreatedAt
+
+	// Wait for the printer's worker so callers still get the print result
+	status, errMsg := s.spooler.SubmitWait(job, 30*time.Second)
+
+	switch status {
+	case "completed":
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "job_id": job.ID})
+	case "failed":
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "job_id": job.ID, "error": errMsg})
+	case "held":
+		message := "Printer unreachable, job queued for retry"
+		if s.spooler.Paused(job.PrinterID) {
+			message = "Printer paused, job queued until it is resumed"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true, "queued": true, "job_id": job.ID,
+			"message": message,
+		})
+	case "cancelled":
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "job_id": job.ID, "error": "Job was cancelled"})
+	case "expired":
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "job_id": job.ID, "error": "Job expired: " + errMsg})
+	case "scheduled":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true, "scheduled": true, "job_id": job.ID, "print_at": job.NotBefore,
+		})
+	default:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true, "queued": true, "job_id": job.ID,
+			"message": "Job is waiting behind other jobs for this printer",
+		})
+	}
 }
 
 // --- Jobs ---
 
+// handleGetJobs lists jobs, newest first, from the recent job buffer and the job history.
+// See parseJobQuery for the filters; pass next_cursor back as cursor for the next page.
 func (s *Server) handleGetJobs(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"jobs": s.jobBuffer.Entries()})
+
+	query, err := parseJobQuery(r.URL.Query())
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+
+	// Recent records are the most current; history fills in jobs that have left the buffer.
+	// One match more than a page tells whether there is a next page.
+	records := s.jobBuffer.Entries()
+	if s.jobHistory != nil {
+		recent := make(map[string]bool, len(records))
+		for _, rec := range records {
+			recent[rec.ID] = true
+		}
+		older, err := s.jobHistory.Find(query, query.Limit+1, recent)
+		if err != nil {
+			s.logBuffer.LogWarn("Failed to read job history: %v", err)
+		}
+		records = append(records, older...)
+	}
+
+	page, next := query.run(records)
+	resp := map[string]interface{}{"success": true, "jobs": page}
+	if next != "" {
+		resp["next_cursor"] = next
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// jobRecordFor builds a job list record from the spooler's copy of a job
+func (s *Server) jobRecordFor(job jobs.Job, status, errMsg string) JobRecord {
+	rec := JobRecord{
+		ID:            job.ID,
+		PrinterID:     job.PrinterID,
+		PrinterName:   s.printerName(job.PrinterID),
+		Status:        status,
+		DataSize:      len(job.Data),
+		Copies:        job.Options.CopyCount(),
+		CopiesPrinted: job.CopiesPrinted,
+		CreatedAt:     job.CreatedAt,
+		Error:         errMsg,
+		Timing:        job.Timing,
+	}
+	if job.NotBefore.After(job.CreatedAt) {
+		rec.PrintAt = &job.NotBefore
+	}
+	return rec
+}
+
+// recordHistory appends a finished job's record to the job history. The job list's
+// record is used while it has one; a job that waited behind enough others to be
+// evicted from the list is recorded from the spooler's copy instead.
+func (s *Server) recordHistory(job jobs.Job, status, errMsg string) {
+	if s.jobHistory == nil {
+		return
+	}
+	rec, ok := s.jobBuffer.Get(job.ID)
+	if !ok {
+		rec = s.jobRecordFor(job, status, errMsg)
+		now := time.Now()
+		rec.CompletedAt = &now
+	}
+	if err := s.jobHistory.Append(rec); err != nil {
+		s.logBuffer.LogWarn("Failed to record job %s in history: %v", job.ID, err)
+	}
+}
+
+// findJob returns a job's record from the job list, or from the history once it has
+// left the list
+func (s *Server) findJob(jobID string) (JobRecord, bool) {
+	if rec, ok := s.jobBuffer.Get(jobID); ok {
+		return rec, true
+	}
+	if s.jobHistory == nil {
+		return JobRecord{}, false
+	}
+	found, err := s.jobHistory.Find(JobQuery{ID: jobID}, 1, nil)
+	if err != nil {
+		s.logBuffer.LogWarn("Failed to read job history: %v", err)
+	}
+	if len(found) == 0 {
+		return JobRecord{}, false
+	}
+	return found[0], true
+}
+
+// ReprintRequest optionally redirects a reprint to another printer
+type ReprintRequest struct {
+	PrinterID string `json:"printer_id,omitempty"`
+	Copies    int    `json:"copies,omitempty"`
+}
+
+func (s *Server) handleReprintJob(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ReprintRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "Invalid request body"})
+			return
+		}
+	}
+
+	original, ok := s.findJob(jobID)
+	if !ok {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "Job not found"})
+		return
+	}
+	data, options, ok := s.jobBuffer.Payload(jobID)
+	if !ok {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "Job data is no longer available for reprinting"})
+		return
+	}
+
+	printerID := original.PrinterID
+	if req.PrinterID != "" {
+		printerID = req.PrinterID
+	}
+	if _, err := s.printerManager.GetPrinter(printerID); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+
+	// One copy unless asked otherwise, and don't pop the cash drawer again;
+	// the other options (cut, feed, ...) carry over
+	options.Copies = req.Copies
+	options.OpenDrawer = false
+	s.logBuffer.LogInfo("Reprinting job %s on %s", jobID, printerID)
+	s.printLocal(w, JobRecord{PrinterID: printerID, ReprintOf: jobID}, jobs.Job{
+		PrinterID: printerID,
+		Data:      data,
+		Options:   options,
+	})
+}
+
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	w.Header().Set("Content-Type", "application/json")
+
+	inFlight, err := s.spooler.Cancel(jobID)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+
+	if inFlight {
+		s.logBuffer.LogInfo("Cancelling job %s while it prints", jobID)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true, "cancelling": true,
+			"message": "Job is printing; it will stop after the chunk being written",
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "cancelled": true})
+}
+
+// handlePreviewJob renders a job's data as it would print on its printer's paper:
+// ?format=png (default), txt or html. ?paper_width=58|80 overrides the printer's width.
+func (s *Server) handlePreviewJob(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+
+	fail := func(code int, msg string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": msg})
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "png"
+	}
+	if format != "png" && format != "txt" && format != "html" {
+		fail(http.StatusBadRequest, "format must be png, txt or html")
+		return
+	}
+
+	// Waiting and printing jobs are in the spooler; finished ones keep their payload for a while
+	var printerID string
+	var data []byte
+	var options jobs.Options
+	if job, ok := s.spooler.Job(jobID); ok {
+		printerID, data, options = job.PrinterID, job.Data, job.Options
+	} else if rec, ok := s.findJob(jobID); ok {
+		if data, options, ok = s.jobBuffer.Payload(jobID); !ok {
+			fail(http.StatusNotFound, "Job data is no longer available for preview")
+			return
+		}
+		printerID = rec.PrinterID
+	} else {
+		fail(http.StatusNotFound, "Job not found")
+		return
+	}
+
+	paperWidth := s.paperWidth(printerID)
+	if v := r.URL.Query().Get("paper_width"); v != "" {
+		if paperWidth, _ = strconv.Atoi(v); paperWidth != 58 && paperWidth != 80 {
+			fail(http.StatusBadRequest, "paper_width must be 58 or 80")
+			return
+		}
+	}
+
+	// Show the last copy, which carries every option (feed, cut, drawer, beep)
+	doc := preview.Parse(options.CopyData(data, options.CopyCount()-1), paperWidth)
+	switch format {
+	case "txt":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, doc.Text())
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, doc.HTML())
+	default:
+		var buf bytes.Buffer
+		if err := doc.PNG(&buf); err != nil {
+			fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(buf.Bytes())
+	}
+}
+
+// paperWidth returns a printer's paper width in mm (80 if unset or unknown)
+func (s *Server) paperWidth(printerID string) int {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+
+	for _, p := range s.config.Printers {
+		if p.ID == printerID && p.PaperWidth != 0 {
+			return p.PaperWidth
+		}
+	}
+	return 80
 }
 
 // --- Logs ---
@@ -775,24 +1674,51 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 		"connected":    false,
 		"reconnecting": false,
 	}
+	transport := map[string]interface{}{"active": connMethod}
 
-	if s.wsClient != nil {
-		connMethod = "websocket"
-		status := s.wsClient.Status()
+	if t := s.cloudTransport(); t != nil {
+		status := t.Status()
+		connMethod = status.Transport
 		cloudConnected = status.Connected
-		wsStatus["connected"] = status.Connected
-		wsStatus["reconnecting"] = status.Reconnecting
-		if status.LastError != "" {
-			wsStatus["last_error"] = status.LastError
+
+		// A supervised WebSocket has its own state while polling stands in for it
+		if ws := status.WebSocket; ws != nil {
+			wsStatus["connected"] = ws.Connected
+			wsStatus["reconnecting"] = ws.Reconnecting
+			if ws.LastError != "" {
+				wsStatus["last_error"] = ws.LastError
+			}
+		}
+
+		reason := status.Reason
+		if reason == "" && !wsEnabled {
+			reason = "use_websocket is off"
+		}
+		transport = map[string]interface{}{"active": status.Transport, "reason": reason}
+		if !status.Since.IsZero() {
+			transport["since"] = status.Since
+		}
+		if status.WebSocket != nil {
+			transport["websocket_failures"] = status.WSFailures
 		}
-	} else if s.pollClient != nil {
-		connMethod = "polling"
-		status := s.pollClient.Status()
-		cloudConnected = status.Connected
-		wsStatus["connected"] = status.Connected
 		if status.LastError != "" {
-			wsStatus["last_error"] = status.LastError
+			transport["last_error"] = status.LastError
 		}
+	} else if isRegistered {
+		transport["reason"] = "cloud client not started"
+	} else {
+		transport["reason"] = "not registered"
+	}
+
+	pendingUpdates := 0
+	if s.outbox != nil {
+		pendingUpdates = s.outbox.Len()
+	}
+	history := map[string]interface{}{}
+	if s.jobHistory != nil {
+		records, bytes := s.jobHistory.Size()
+		history["records"] = records
+		history["bytes"] = bytes
 	}
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -800,10 +1726,13 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 		"cloud_connected":   cloudConnected,
 		"connection_method": connMethod,
 		"websocket":         wsStatus,
+		"transport":         transport,
 		"printers_count":    printerCount,
 		"is_registered":     isRegistered,
 		"tenant":            tenant,
 		"server_name":       serverName,
+		"pending_updates":   pendingUpdates,
+		"job_history":       history,
 	})
 }
 
@@ -823,91 +1752,255 @@ func (s *Server) getPrinterList() []map[string]interface{} {
 			"name":        p.Name,
 			"type":        p.Type,
 			"paper_width": pw,
+			"status":      s.printerStatus(p.ID, s.printerManager.Health(p.ID)),
 		})
 	}
 	return printers
 }
 
-// getPrinterStatuses returns current printer statuses for heartbeat reporting
-func (s *Server) getPrinterStatuses() map[string]string {
+// getPrinterSnapshot returns the cached status of every configured printer
+func (s *Server) getPrinterSnapshot() []cloud.PrinterStatus {
 	s.configMu.RLock()
 	defer s.configMu.RUnlock()
 
-	statuses := make(map[string]string)
+	snapshot := make([]cloud.PrinterStatus, 0, len(s.config.Printers))
 	for _, p := range s.config.Printers {
-		status := "unknown"
-		if mgdPrinter, err := s.printerManager.GetPrinter(p.ID); err == nil {
-			status = mgdPrinter.Status()
-		}
-		statuses[p.ID] = status
-	}
-	return statuses
-}
-
-// configurePollClient sets up all callbacks on a PollClient instance
-func (s *Server) configurePollClient(pc *cloud.PollClient) {
-	pc.PrinterStatuses = s.getPrinterStatuses
-	pc.PrinterList = s.getPrinterList
-	pc.OnJobReceived = func(jobID, printerID string, dataSize int) {
-		printerName := ""
-		s.configMu.RLock()
-		for _, p := range s.config.Printers {
-			if p.ID == printerID {
-				printerName = p.Name
-				break
-			}
+		snapshot = append(snapshot, s.cloudPrinterStatus(p.ID, s.printerManager.Health(p.ID)))
+	}
+	return snapshot
+}
+
+// handlePrinterStatusChange logs a printer status change and pushes it to the cloud
+func (s *Server) handlePrinterStatusChange(printerID string, health printer.PrinterHealth) {
+	if printer.IsUp(health.Status) {
+		s.logBuffer.LogInfo("Printer %s is %s", printerID, health.Status)
+	} else {
+		s.logBuffer.LogWarn("Printer %s is %s", printerID, health.Status)
+	}
+
+	if t := s.cloudTransport(); t != nil {
+		t.SendPrinterStatus(s.cloudPrinterStatus(printerID, health))
+	}
+
+	// Retry queued jobs as soon as a printer is back
+	if printer.IsUp(health.Status) {
+		s.spooler.Kick(printerID)
+	}
+}
+
+// handleJobStatus records a job status change from the spooler and reports it to the cloud
+func (s *Server) handleJobStatus(job jobs.Job, status, errMsg string) {
+	switch status {
+	case "completed":
+		s.logBuffer.LogInfo("Job %s printed on %s", job.ID, job.PrinterID)
+	case "failed":
+		s.logBuffer.LogError("Job %s failed: %s", job.ID, errMsg)
+	case "retrying":
+		s.logBuffer.LogWarn("Job %s on %s: %s", job.ID, job.PrinterID, errMsg)
+	case "held":
+		if s.spooler.Paused(job.PrinterID) {
+			s.logBuffer.LogInfo("Printer %s paused, job %s held", job.PrinterID, job.ID)
+		} else {
+			s.logBuffer.LogWarn("Printer %s unreachable, job %s held for retry", job.PrinterID, job.ID)
 		}
-		s.configMu.RUnlock()
+	case "cancelled":
+		s.logBuffer.LogInfo("Job %s cancelled", job.ID)
+	case "expired":
+		s.logBuffer.LogWarn("Job %s on %s expired: %s", job.ID, job.PrinterID, errMsg)
+	case "scheduled":
+		s.logBuffer.LogInfo("Job %s scheduled for %s", job.ID, job.NotBefore.Local().Format(time.RFC1123))
+		s.jobBuffer.SetPrintAt(job.ID, job.NotBefore)
+	}
+	if status == "completed" {
+		s.jobBuffer.UpdateCopies(job.ID, job.CopiesPrinted)
+	}
+	if status == "completed" || status == "failed" || status == "cancelled" || status == "expired" {
+		s.jobBuffer.StorePayload(job.ID, job.Data, job.Options)
+	}
+	s.jobBuffer.UpdateTiming(job.ID, job.Timing)
+	s.jobBuffer.UpdateStatus(job.ID, status, errMsg)
+	if status == "completed" || status == "failed" || status == "cancelled" || status == "expired" {
+		s.recordHistory(job, status, errMsg)
+	}
+	if job.Source != jobs.SourceLocal {
+		s.reportJobStatus(job, status, errMsg)
+	}
+}
 
-		s.jobBuffer.Add(JobRecord{
-			ID:          jobID,
-			PrinterID:   printerID,
-			PrinterName: printerName,
-			Status:      "printing",
-			DataSize:    dataSize,
-			CreatedAt:   time.Now(),
-		})
+// retryPolicy returns a printer's retry policy: its own overrides on top of the jobs defaults
+func (s *Server) retryPolicy(printerID string) jobs.RetryPolicy {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+
+	policy := applyRetryConfig(jobs.DefaultRetryPolicy(), s.config.Jobs.Retry)
+	for _, p := range s.config.Printers {
+		if p.ID == printerID && p.Retry != nil {
+			policy = applyRetryConfig(policy, *p.Retry)
+			break
+		}
 	}
-	pc.OnJobCompleted = func(jobID, status, errMsg string) {
-		s.jobBuffer.UpdateStatus(jobID, status, errMsg)
+	return policy
+}
+
+// applyRetryConfig overrides policy with the non-zero fields of rc
+func applyRetryConfig(policy jobs.RetryPolicy, rc config.RetryConfig) jobs.RetryPolicy {
+	if rc.MaxAttempts > 0 {
+		policy.MaxAttempts = rc.MaxAttempts
+	}
+	if rc.InitialBackoff > 0 {
+		policy.InitialBackoff = rc.InitialBackoff
+	}
+	if rc.MaxBackoff > 0 {
+		policy.MaxBackoff = rc.MaxBackoff
 	}
+	if rc.RetryOn != nil {
+		policy.RetryOn = rc.RetryOn
+	}
+	return policy
 }
 
-// configureWSClient sets up all callbacks on a WSClient instance
-func (s *Server) configureWSClient(ws *cloud.WSClient) {
-	ws.PrinterList = s.getPrinterList
-	ws.OnJobReceived = func(jobID, printerID string, dataSize int) {
-		printerName := ""
-		s.configMu.RLock()
-		for _, p := range s.config.Printers {
-			if p.ID == printerID {
-				printerName = p.Name
-				break
-			}
+// reportJobStatus reports a job status and its timings to the cloud through the outbox,
+// or directly over the transport if the outbox is unavailable
+func (s *Server) reportJobStatus(job jobs.Job, status, errMsg string) {
+	if s.outbox != nil {
+		timing := job.Timing
+		s.outbox.Add(cloud.StatusUpdate{JobID: job.ID, Status: status, Error: errMsg, Timing: &timing})
+	} else if t := s.cloudTransport(); t != nil {
+		t.SendStatus(job.ID, status, errMsg)
+	}
+}
+
+// reportStatus reports the status of a job the executor did not queue, such as a
+// duplicate or a job whose data could not be decoded
+func (s *Server) reportStatus(jobID, status, errMsg string) {
+	if s.outbox != nil {
+		s.outbox.Add(cloud.StatusUpdate{JobID: jobID, Status: status, Error: errMsg})
+	} else if t := s.cloudTransport(); t != nil {
+		t.SendStatus(jobID, status, errMsg)
+	}
+}
+
+// deliverStatus sends an outbox update over the transport
+func (s *Server) deliverStatus(u cloud.StatusUpdate) error {
+	if t := s.cloudTransport(); t != nil {
+		return t.DeliverStatus(u)
+	}
+	return cloud.ErrNotConnected
+}
+
+// printerName returns the configured name of a printer, or "" if unknown
+func (s *Server) printerName(printerID string) string {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+
+	for _, p := range s.config.Printers {
+		if p.ID == printerID {
+			return p.Name
 		}
-		s.configMu.RUnlock()
+	}
+	return ""
+}
 
-		s.jobBuffer.Add(JobRecord{
-			ID:          jobID,
-			PrinterID:   printerID,
-			PrinterName: printerName,
-			Status:      "printing",
-			DataSize:    dataSize,
-			CreatedAt:   time.Now(),
-		})
+// printerStatus returns the status to report for a printer: "paused" while staff
+// have it paused, otherwise its probed health
+func (s *Server) printerStatus(printerID string, health printer.PrinterHealth) string {
+	if s.spooler.Paused(printerID) {
+		return printer.StatusPaused
 	}
-	ws.OnJobCompleted = func(jobID, status, errMsg string) {
-		s.jobBuffer.UpdateStatus(jobID, status, errMsg)
+	return health.Status
+}
+
+// cloudPrinterStatus converts cached printer health to its cloud representation.
+// For a paused printer, Reasons still carries the probed conditions.
+func (s *Server) cloudPrinterStatus(printerID string, health printer.PrinterHealth) cloud.PrinterStatus {
+	return cloud.PrinterStatus{
+		PrinterID: printerID,
+		Status:    s.printerStatus(printerID, health),
+		Reasons:   health.Reasons,
+		CheckedAt: health.CheckedAt,
 	}
 }
 
-// syncPrintersToCloud triggers a printer sync on whichever cloud client is active
-func (s *Server) syncPrintersToCloud() {
-	if s.pollClient != nil {
-		s.pollClient.SyncPrinters()
+// setPrinterPaused pauses or resumes a printer, saves the state and tells the cloud
+func (s *Server) setPrinterPaused(printerID string, paused bool) error {
+	s.configMu.Lock()
+	found := false
+	for i := range s.config.Printers {
+		if s.config.Printers[i].ID == printerID {
+			s.config.Printers[i].Paused = paused
+			found = true
+			break
+		}
 	}
-	if s.wsClient != nil {
-		s.wsClient.SyncPrinters()
+	if !found {
+		s.configMu.Unlock()
+		return fmt.Errorf("printer not found: %s", printerID)
+	}
+	if s.config.ConfigPath != "" {
+		if err := s.config.Save(s.config.ConfigPath); err != nil {
+			s.logBuffer.LogError("Failed to save config; printer %s will not stay paused=%v after a restart: %v", printerID, paused, err)
+		}
+	}
+	s.configMu.Unlock()
+
+	if paused {
+		s.spooler.Pause(printerID)
+		s.logBuffer.LogInfo("Printer %s paused; jobs will queue until it is resumed", printerID)
+	} else {
+		s.spooler.Resume(printerID)
+		s.logBuffer.LogInfo("Printer %s resumed (%d job(s) waiting)", printerID, s.spooler.Depth(printerID))
+	}
+
+	if t := s.cloudTransport(); t != nil {
+		t.SendPrinterStatus(s.cloudPrinterStatus(printerID, s.printerManager.Health(printerID)))
+	}
+	s.syncPrintersToCloud()
+	return nil
+}
+
+// newTransport creates the cloud transport the configuration asks for
+func (s *Server) newTransport() cloud.Transport {
+	hooks := cloud.Hooks{
+		PrinterList:     s.getPrinterList,
+		PrinterSnapshot: s.getPrinterSnapshot,
+		OnSwitch: func(transport, reason string) {
+			if transport == cloud.TransportPolling {
+				s.logBuffer.LogWarn("Cloud transport switched to HTTP polling: %s", reason)
+			} else {
+				s.logBuffer.LogInfo("Cloud transport switched back to WebSocket: %s", reason)
+			}
+		},
+	}
+	if s.outbox != nil {
+		hooks.OnConnected = s.outbox.Flush
+	}
+
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return cloud.NewTransport(&s.config.Cloud, s.executor, hooks)
+}
+
+// handleCloudJobReceived adds a job from the cloud to the job list
+func (s *Server) handleCloudJobReceived(jobID, printerID string, dataSize, copies int) {
+	// A redelivery racing the first delivery keeps the first record
+	if _, ok := s.jobBuffer.Get(jobID); ok {
+		return
+	}
+	s.jobBuffer.Add(JobRecord{
+		ID:          jobID,
+		PrinterID:   printerID,
+		PrinterName: s.printerName(printerID),
+		Status:      "queued",
+		DataSize:    dataSize,
+		Copies:      copies,
+		CreatedAt:   time.Now(),
+	})
+}
+
+// syncPrintersToCloud triggers a printer sync over the cloud transport
+func (s *Server) syncPrintersToCloud() {
+	if t := s.cloudTransport(); t != nil {
+		t.SyncPrinters()
 	}
 }
 
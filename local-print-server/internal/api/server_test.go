@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jetsetgo/local-print-server/internal/config"
+)
+
+func TestUpdatePrinterRejectsWithoutChanges(t *testing.T) {
+	original := config.PrinterConfig{ID: "p1", Name: "Front", Type: "usb", Address: "10.0.0.5", Port: 9100}
+	s := &Server{
+		config:    &config.Config{Printers: []config.PrinterConfig{original}},
+		logBuffer: NewLogBuffer(10),
+	}
+
+	for _, body := range []string{
+		`{"name":"Back","address":"10.0.0.6","retry":"often"}`,
+		`{"name":"Back","retry":{"initial_backoff":"soon"}}`,
+		`{"name":"Back","pin_by":"hostname"}`,
+		`{"name":"Back","chunk_timeout":"forever"}`,
+		`{"name":"Back","chunk_timeout":"-1s"}`,
+		`{"name":"Back","chunk_size":-1}`,
+		`{"name":"Back","max_bytes_per_sec":-500}`,
+		`{"name":"Back","profile":"nope"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/api/printers/p1", strings.NewReader(body))
+		req.SetPathValue("id", "p1")
+		rec := httptest.NewRecorder()
+		s.handleUpdatePrinter(rec, req)
+
+		var resp map[string]interface{}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if resp["success"] != false {
+			t.Errorf("%s: response %v, want an error", body, resp)
+		}
+		if got := s.config.Printers[0]; got.Name != original.Name || got.Address != original.Address {
+			t.Errorf("%s: printer changed to %+v", body, got)
+		}
+	}
+}
+
+func TestApplyPrinterUpdates(t *testing.T) {
+	pc, err := applyPrinterUpdates(config.PrinterConfig{ID: "p1", Name: "Front"}, map[string]interface{}{
+		"name": "Back", "pin_by": "mac", "mac": "00-1B-A9-AA-BB-CC", "chunk_timeout": "5s",
+		"retry": map[string]interface{}{"max_attempts": float64(3)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pc.Name != "Back" || pc.PinBy != "mac" || pc.MAC != "00:1b:a9:aa:bb:cc" || pc.ChunkTimeout.String() != "5s" {
+		t.Errorf("updated printer = %+v", pc)
+	}
+	if pc.Retry == nil || pc.Retry.MaxAttempts != 3 {
+		t.Errorf("retry = %+v, want 3 attempts", pc.Retry)
+	}
+}
@@ -85,9 +85,10 @@ a:hover{text-decoration:underline}
 .p-actions{display:flex;gap:6px}
 
 /* Jobs table */
-.job-row{display:grid;grid-template-columns:120px 1fr 90px 100px 70px;gap:8px;padding:10px 0;border-bottom:1px solid #f0f0f0;font-size:13px;align-items:center;animation:slideIn .3s ease}
+.job-row{display:grid;grid-template-columns:120px 1fr 90px 100px 70px 70px;gap:8px;padding:10px 0;border-bottom:1px solid #f0f0f0;font-size:13px;align-items:center;animation:slideIn .3s ease}
 .job-row:last-child{border:none}
 .job-id{font-family:'SF Mono','Cascadia Code','Courier New',monospace;font-size:12px;color:#666;overflow:hidden;text-overflow:ellipsis;white-space:nowrap}
+.job-id a{color:inherit;text-decoration:underline dotted}
 .job-hdr{font-weight:600;color:#555;font-size:12px;text-transform:uppercase;letter-spacing:.05em}
 
 /* Logs */
@@ -573,7 +574,9 @@ function refreshDashboard() {
    dot.className = 'hero-dot dot-green dot-pulse';
    text.textContent = 'Connected to JetSetGo';
    var method = data.connection_method === 'websocket' ? 'WebSocket active' : 'Polling every ' + (configData && configData.cloud ? configData.cloud.poll_interval : '5s');
+   if (data.connection_method === 'polling' && data.websocket && data.websocket.enabled) method += ' (WebSocket unavailable)';
    sub.textContent = method;
+   sub.title = data.transport && data.transport.reason ? data.transport.reason : '';
   } else if (data.websocket && data.websocket.reconnecting) {
    dot.className = 'hero-dot dot-yellow dot-pulse';
    text.textContent = 'Reconnecting...';
@@ -616,12 +619,14 @@ function refreshDashboard() {
    var html = '<div style="display:grid;grid-template-columns:repeat(auto-fill,minmax(250px,1fr));gap:10px">';
    for (var i = 0; i < printers.length; i++) {
     var p = printers[i];
-    var sc = p.status === 'online' ? 'dot-green' : (p.status === 'offline' ? 'dot-red' : 'dot-yellow');
+    var sc = statusDot(p.status);
     var tc = p.type === 'network' ? 'badge-blue' : 'badge-purple';
     html += '<div class="card" style="padding:14px;margin:0">';
     html += '<div style="display:flex;align-items:center;gap:6px;margin-bottom:4px"><span class="sdot ' + sc + '"></span><strong>' + esc(p.name) + '</strong></div>';
     html += '<div class="p-badges"><span class="badge badge-blue">' + esc(p.type) + '</span>';
-    html += '<span class="badge badge-gray">' + (p.paper_width || 80) + 'mm</span></div>';
+    html += '<span class="badge badge-gray">' + (p.paper_width || 80) + 'mm</span>';
+    if (p.queue_depth) html += '<span class="badge badge-yellow">' + p.queue_depth + ' queued</span>';
+    html += '</div>';
     html += '<button class="btn btn-secondary btn-sm" onclick="testPrint(\'' + esc(p.id) + '\')" style="margin-top:8px">Test Print</button>';
     html += '</div>';
    }
@@ -637,17 +642,26 @@ function refreshDashboard() {
   if (jobs.length === 0) {
    container.innerHTML = '<div class="empty"><svg width="40" height="40" viewBox="0 0 24 24" fill="none" stroke="#ccc" stroke-width="1.5"><rect x="6" y="2" width="12" height="20" rx="1"/><path d="M6 18h12"/><path d="M6 14h12"/></svg><p>No print jobs yet. Send a test print to verify your setup.</p></div>';
   } else {
-   var html = '<div class="job-row job-hdr"><span>Job ID</span><span>Printer</span><span>Status</span><span>Time</span><span>Size</span></div>';
+   var html = '<div class="job-row job-hdr"><span>Job ID</span><span>Printer</span><span>Status</span><span>Time</span><span>Size</span><span></span></div>';
    var limit = Math.min(jobs.length, 20);
    for (var i = 0; i < limit; i++) {
     var j = jobs[i];
-    var bc = j.status === 'completed' ? 'badge-green' : (j.status === 'failed' ? 'badge-red' : (j.status === 'printing' ? 'badge-blue' : 'badge-yellow'));
+    var bc = jobBadge(j.status);
     html += '<div class="job-row">';
-    html += '<span class="job-id" title="' + esc(j.id) + '">' + esc(j.id.length > 14 ? j.id.substring(0,14) + '..' : j.id) + '</span>';
+    var shortId = esc(j.id.length > 14 ? j.id.substring(0,14) + '..' : j.id);
+    if (isActiveJob(j.status) || j.reprintable) shortId = '<a href="/api/jobs/' + encodeURIComponent(j.id) + '/preview?format=html" target="_blank" title="Preview">' + shortId + '</a>';
+    html += '<span class="job-id" title="' + esc(j.id) + '">' + shortId + '</span>';
     html += '<span>' + esc(j.printer_name || j.printer_id) + '</span>';
-    html += '<span class="badge ' + bc + '">' + esc(j.status) + '</span>';
+    var label = j.status === 'scheduled' && j.print_at ? 'at ' + new Date(j.print_at).toLocaleTimeString([], {hour:'2-digit', minute:'2-digit'}) : j.status;
+    if (j.status === 'sending' && j.bytes_total) label += ' ' + Math.floor(100 * j.bytes_sent / j.bytes_total) + '%';
+    var title = j.print_at ? 'Scheduled for ' + new Date(j.print_at).toLocaleString() : jobTimingText(j.timing);
+    html += '<span class="badge ' + bc + '"' + (title ? ' title="' + esc(title) + '"' : '') + '>' + esc(label) + (j.copies > 1 ? ' ' + j.copies_printed + '/' + j.copies : '') + '</span>';
     html += '<span style="font-size:12px;color:#888">' + timeAgo(j.created_at) + '</span>';
     html += '<span style="font-size:12px;color:#888">' + formatBytes(j.data_size) + '</span>';
+    var action = '';
+    if (isActiveJob(j.status)) action = '<button class="btn btn-danger btn-sm" onclick="cancelJob(\'' + esc(j.id) + '\')">Cancel</button>';
+    else if (j.reprintable) action = '<button class="btn btn-secondary btn-sm" onclick="reprintJob(\'' + esc(j.id) + '\',\'' + esc(j.printer_id) + '\')">Reprint</button>';
+    html += '<span>' + action + '</span>';
     html += '</div>';
    }
    container.innerHTML = html;
@@ -708,17 +722,22 @@ function refreshPrinters() {
    var html = '';
    for (var i = 0; i < printers.length; i++) {
     var p = printers[i];
-    var sc = p.status === 'online' ? 'dot-green' : (p.status === 'offline' ? 'dot-red' : 'dot-yellow');
-    var stext = p.status === 'online' ? 'Online' : (p.status === 'offline' ? 'Offline' : 'Unknown');
+    var sc = statusDot(p.status);
+    var stext = statusLabel(p.status);
     html += '<div class="p-card" id="pc-' + esc(p.id) + '">';
     html += '<div class="p-info">';
     html += '<h4><span class="sdot ' + sc + '"></span>' + esc(p.name) + '</h4>';
     html += '<p>' + esc(p.type === 'network' ? p.address + ':' + p.port : 'USB') + ' &middot; ' + stext + '</p>';
     html += '<div class="p-badges"><span class="badge badge-blue">' + esc(p.type) + '</span>';
-    html += '<span class="badge badge-gray">' + (p.paper_width || 80) + 'mm</span></div>';
+    html += '<span class="badge badge-gray">' + (p.paper_width || 80) + 'mm</span>';
+    if (p.queue_depth) html += '<span class="badge badge-yellow">' + p.queue_depth + ' queued</span>';
+    html += '</div>';
     html += '</div>';
     html += '<div class="p-actions">';
     html += '<button class="btn btn-primary btn-sm" onclick="testPrint(\'' + esc(p.id) + '\')">Test Print</button>';
+    if (p.paused) html += '<button class="btn btn-secondary btn-sm" onclick="setPrinterPaused(\'' + esc(p.id) + '\',false)">Resume</button>';
+    else html += '<button class="btn btn-secondary btn-sm" onclick="setPrinterPaused(\'' + esc(p.id) + '\',true)">Pause</button>';
+    html += '<button class="btn btn-secondary btn-sm" onclick="showPrinterHistory(\'' + esc(p.id) + '\',\'' + esc(p.name) + '\')">History</button>';
     html += '<button class="btn btn-secondary btn-sm" onclick="editPrinter(\'' + esc(p.id) + '\')">Edit</button>';
     html += '<button class="btn btn-danger btn-sm" onclick="confirmDeletePrinter(\'' + esc(p.id) + '\',\'' + esc(p.name) + '\')">Remove</button>';
     html += '</div></div>';
@@ -795,8 +814,11 @@ function scanPrinters() {
    var html = '';
    for (var i = 0; i < results.length; i++) {
     var r = results[i];
-    html += '<div class="p-card"><div class="p-info"><h4>' + esc(r.name) + '</h4><p>' + esc(r.address) + ':' + r.port + '</p></div>';
-    html += '<button class="btn btn-primary btn-sm" onclick="prefillPrinter(\'' + esc(r.address) + '\',' + r.port + ',\'' + esc(r.name) + '\')">Add</button></div>';
+    var width = r.info && r.info.suggested_paper_width ? r.info.suggested_paper_width : 80;
+    var detail = esc(r.address) + ':' + r.port;
+    if (r.info && r.info.serial) detail += ' &middot; S/N ' + esc(r.info.serial);
+    html += '<div class="p-card"><div class="p-info"><h4>' + esc(r.name) + '</h4><p>' + detail + '</p></div>';
+    html += '<button class="btn btn-primary btn-sm" onclick="prefillPrinter(\'' + esc(r.address) + '\',' + r.port + ',\'' + esc(r.name) + '\',' + width + ')">Add</button></div>';
    }
    list.innerHTML = html;
   }
@@ -807,14 +829,83 @@ function scanPrinters() {
  });
 }
 
-function prefillPrinter(address, port, name) {
+function prefillPrinter(address, port, name, width) {
  toggleAddPrinter(true);
  document.getElementById('ap-name').value = name;
  document.getElementById('ap-address').value = address;
  document.getElementById('ap-port').value = port;
+ if (width) document.getElementById('ap-width').value = width;
  document.getElementById('ap-id').value = slugify(name);
 }
 
+function isActiveJob(status) {
+ return ['queued', 'scheduled', 'dispatched', 'sending', 'retrying', 'held'].indexOf(status) >= 0;
+}
+
+function jobBadge(status) {
+ if (status === 'completed') return 'badge-green';
+ if (status === 'failed' || status === 'expired') return 'badge-red';
+ if (status === 'dispatched' || status === 'sending') return 'badge-blue';
+ if (status === 'cancelled') return 'badge-gray';
+ return 'badge-yellow';
+}
+
+// jobTimingText describes where a job's time went: waiting in the queue, connecting and sending
+function jobTimingText(t) {
+ if (!t || !t.received_at) return '';
+ var ms = function(a, b) { return Math.max(0, new Date(b) - new Date(a)) + 'ms'; };
+ var parts = [];
+ if (t.dequeued_at) parts.push('Queue ' + ms(t.received_at, t.dequeued_at));
+ if (t.dequeued_at && t.connected_at) parts.push('connect ' + ms(t.dequeued_at, t.connected_at));
+ if (t.connected_at && t.written_at) parts.push('send ' + ms(t.connected_at, t.written_at));
+ if (t.written_at) parts.push('total ' + ms(t.received_at, t.written_at));
+ return parts.join(', ');
+}
+
+function cancelJob(id) {
+ fetch('/api/jobs/' + encodeURIComponent(id), {method:'DELETE'}).then(function(r){return r.json()}).then(function(data) {
+  if (data.cancelling) toast('Job is stopping', 'info');
+  else if (data.success) toast('Job cancelled', 'success');
+  else toast('Cancel failed: ' + (data.error || 'Unknown error'), 'error');
+  refreshDashboard();
+ }).catch(function(){ toast('Network error', 'error'); });
+}
+
+function setPrinterPaused(id, paused) {
+ fetch('/api/printers/' + encodeURIComponent(id) + (paused ? '/pause' : '/resume'), {method:'POST'}).then(function(r){return r.json()}).then(function(data) {
+  if (data.success) toast(paused ? 'Printer paused; jobs will queue' : 'Printer resumed', 'success');
+  else toast((paused ? 'Pause' : 'Resume') + ' failed: ' + (data.error || 'Unknown error'), 'error');
+  refreshPrinters();
+ }).catch(function(){ toast('Network error', 'error'); });
+}
+
+function reprintJob(id, printerId) {
+ fetch('/api/printers').then(function(r){return r.json()}).then(function(data) {
+  var opts = '';
+  var printers = data.printers || [];
+  for (var i = 0; i < printers.length; i++) {
+   opts += '<option value="' + esc(printers[i].id) + '"' + (printers[i].id === printerId ? ' selected' : '') + '>' + esc(printers[i].name) + '</option>';
+  }
+  showModal(
+   'Reprint Job',
+   '<div class="form-group"><label>Printer</label><select id="rp-printer">' + opts + '</select></div>' +
+   '<div class="form-group"><label>Copies</label><input type="number" id="rp-copies" value="1" min="1" max="20"></div>',
+   function() {
+    var body = {printer_id: document.getElementById('rp-printer').value, copies: parseInt(document.getElementById('rp-copies').value) || 1};
+    closeModal();
+    toast('Reprinting...', 'info');
+    fetch('/api/jobs/' + encodeURIComponent(id) + '/reprint', {
+     method:'POST', headers:{'Content-Type':'application/json'}, body:JSON.stringify(body)
+    }).then(function(r){return r.json()}).then(function(d) {
+     if (d.success) toast(d.queued ? (d.message || 'Reprint queued') : 'Reprint sent!', 'success');
+     else toast('Reprint failed: ' + (d.error || 'Unknown error'), 'error');
+     refreshDashboard();
+    }).catch(function(){ toast('Network error', 'error'); });
+   }
+  );
+ }).catch(function(){ toast('Network error', 'error'); });
+}
+
 function testPrint(id) {
  toast('Sending test print...', 'info');
  fetch('/api/printers/' + encodeURIComponent(id) + '/test', {method:'POST'}).then(function(r){return r.json()}).then(function(data) {
@@ -836,13 +927,19 @@ function editPrinter(id) {
    'Edit Printer: ' + p.name,
    '<div class="form-group"><label>Name</label><input type="text" id="edit-p-name" value="' + esc(p.name) + '"></div>' +
    (p.type === 'network' ? '<div class="form-row"><div class="form-group"><label>IP Address</label><input type="text" id="edit-p-address" value="' + esc(p.address) + '"></div><div class="form-group"><label>Port</label><input type="number" id="edit-p-port" value="' + p.port + '"></div></div>' : '') +
-   '<div class="form-group"><label>Paper Width</label><select id="edit-p-width"><option value="80"' + (p.paper_width === 80 || !p.paper_width ? ' selected' : '') + '>80mm</option><option value="58"' + (p.paper_width === 58 ? ' selected' : '') + '>58mm</option></select></div>',
+   (p.type === 'network' ? '<div class="form-group"><label>Follow Printer By</label><select id="edit-p-pin">' + pinOptions(p.pin_by) + '</select><div class="form-help">Keeps the printer working when the router assigns it a new IP address</div></div>' : '') +
+   '<div class="form-group"><label>Paper Width</label><select id="edit-p-width"><option value="80"' + (p.paper_width === 80 || !p.paper_width ? ' selected' : '') + '>80mm</option><option value="58"' + (p.paper_width === 58 ? ' selected' : '') + '>58mm</option></select></div>' +
+   '<div class="form-row"><div class="form-group"><label>Profile</label><select id="edit-p-profile">' + selectOptions(['generic', 'epson'], p.profile || 'generic') + '</select></div>' +
+   '<div class="form-group"><label>Code Page</label><select id="edit-p-codepage">' + selectOptions(['cp437', 'cp850', 'cp858', 'cp866', 'cp1252'], p.code_page || 'cp437') + '</select><div class="form-help">Characters for text printing; cp858 has the euro sign</div></div></div>',
    function() {
     var body = {name: document.getElementById('edit-p-name').value.trim()};
     body.paper_width = parseInt(document.getElementById('edit-p-width').value);
+    body.profile = document.getElementById('edit-p-profile').value;
+    body.code_page = document.getElementById('edit-p-codepage').value;
     if (p.type === 'network') {
      body.address = document.getElementById('edit-p-address').value.trim();
      body.port = parseInt(document.getElementById('edit-p-port').value);
+     body.pin_by = document.getElementById('edit-p-pin').value;
     }
     fetch('/api/printers/' + encodeURIComponent(id), {
      method:'PUT', headers:{'Content-Type':'application/json'}, body:JSON.stringify(body)
@@ -856,6 +953,57 @@ function editPrinter(id) {
  });
 }
 
+function selectOptions(values, selected) {
+ var html = '';
+ for (var i = 0; i < values.length; i++) {
+  html += '<option value="' + values[i] + '"' + (values[i] === selected ? ' selected' : '') + '>' + values[i] + '</option>';
+ }
+ return html;
+}
+
+function statusDot(status) {
+ if (status === 'online') return 'dot-green';
+ if (status === 'offline' || status === 'paper_out' || status === 'cover_open' || status === 'error') return 'dot-red';
+ return 'dot-yellow';
+}
+
+function statusLabel(status) {
+ var labels = {paused:'Paused', online:'Online', offline:'Offline', paper_out:'Paper Out', paper_low:'Paper Low', cover_open:'Cover Open', error:'Error'};
+ return labels[status] || 'Unknown';
+}
+
+function showPrinterHistory(id, name) {
+ fetch('/api/printers/' + encodeURIComponent(id) + '/history').then(function(r){return r.json()}).then(function(data) {
+  if (!data.success) { toast(data.error || 'Failed to load history', 'error'); return; }
+  var html = '<div class="form-row">';
+  var windows = ['24h', '7d'];
+  for (var i = 0; i < windows.length; i++) {
+   var st = data.stats[windows[i]];
+   html += '<div class="card" style="padding:12px;margin:0"><h3>Last ' + windows[i] + '</h3>';
+   html += '<div style="font-size:13px">Uptime: <strong>' + (st.observed_seconds > 0 ? st.uptime_percent.toFixed(2) + '%' : 'n/a') + '</strong></div>';
+   html += '<div style="font-size:13px">Outages: ' + st.outages + '</div>';
+   html += '<div style="font-size:13px">MTTR: ' + (st.mttr_seconds ? Math.round(st.mttr_seconds) + 's' : '-') + '</div></div>';
+  }
+  html += '</div><div style="margin-top:12px;max-height:240px;overflow-y:auto">';
+  var tr = data.transitions || [];
+  if (tr.length === 0) html += '<p style="color:#888;font-size:13px">No status changes recorded yet.</p>';
+  for (var j = 0; j < tr.length; j++) {
+   html += '<div style="font-size:12px;padding:3px 0;border-bottom:1px solid #f0f0f0"><span class="sdot ' + statusDot(tr[j].to) + '"></span>' + esc(statusLabel(tr[j].to)) + ' <span style="color:#888">' + new Date(tr[j].at).toLocaleString() + '</span></div>';
+  }
+  html += '</div>';
+  showModal('Status History: ' + esc(name), html, null);
+ }).catch(function(){ toast('Network error', 'error'); });
+}
+
+function pinOptions(current) {
+ var opts = [['address','Fixed IP address'],['mac','MAC address'],['serial','Serial number (GS I)'],['snmp_serial','Serial number (SNMP)']];
+ var html = '';
+ for (var i = 0; i < opts.length; i++) {
+  html += '<option value="' + opts[i][0] + '"' + ((current || 'address') === opts[i][0] ? ' selected' : '') + '>' + opts[i][1] + '</option>';
+ }
+ return html;
+}
+
 function confirmDeletePrinter(id, name) {
  showModal(
   'Remove Printer',
@@ -1013,10 +1161,10 @@ function showModal(title, bodyHtml, onConfirm) {
   '<div class="modal"><h3>' + title + '</h3>' +
   '<div>' + bodyHtml + '</div>' +
   '<div class="btn-row" style="margin-top:16px;justify-content:flex-end">' +
-  '<button class="btn btn-secondary" onclick="closeModal()">Cancel</button>' +
-  '<button class="btn btn-primary" id="modal-confirm">Confirm</button>' +
+  (onConfirm ? '<button class="btn btn-secondary" onclick="closeModal()">Cancel</button>' +
+  '<button class="btn btn-primary" id="modal-confirm">Confirm</button>' : '<button class="btn btn-secondary" onclick="closeModal()">Close</button>') +
   '</div></div></div>';
- document.getElementById('modal-confirm').onclick = onConfirm;
+ if (onConfirm) document.getElementById('modal-confirm').onclick = onConfirm;
 }
 
 function closeModal() {
@@ -0,0 +1,180 @@
+package cloud
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jetsetgo/local-print-server/internal/jobs"
+)
+
+// IncomingJob is a print job from the cloud, as any transport delivers it
+type IncomingJob struct {
+	JobID     string                 `json:"job_id,omitempty"`
+	PrinterID string                 `json:"printer_id,omitempty"`
+	Priority  int                    `json:"priority,omitempty"`
+	Data      json.RawMessage        `json:"data,omitempty"` // Base64 ESC/POS, or the template's data
+	Template  string                 `json:"template,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+
+	// Optional schedule (RFC 3339); the job is held locally until then
+	PrintAt   string `json:"print_at,omitempty"`
+	NotBefore string `json:"not_before,omitempty"`
+}
+
+// Executor queues the jobs every transport receives: it re-acknowledges duplicates,
+// decodes or renders the print data, checks the schedule and reports jobs it can't
+// accept. It also carries out the cloud's cancel and pause commands.
+type Executor struct {
+	spooler *jobs.Spooler
+
+	// Report sends a job status to the cloud
+	Report func(jobID, status, errMsg string)
+
+	// RenderTemplate renders a job sent as template + data instead of ESC/POS
+	RenderTemplate func(printerID, template string, data json.RawMessage, options map[string]interface{}) ([]byte, error)
+
+	// OnPausePrinter pauses or resumes a printer at the cloud's request
+	OnPausePrinter func(printerID string, paused bool) error
+
+	// Callback for job tracking
+	OnJobReceived  func(jobID, printerID string, dataSize, copies int)
+	OnJobCompleted func(jobID, status, errMsg string)
+}
+
+// NewExecutor creates an executor that submits jobs to a spooler
+func NewExecutor(spooler *jobs.Spooler) *Executor {
+	return &Executor{spooler: spooler}
+}
+
+// Execute validates a job received over a transport (jobs.SourceWebSocket or
+// jobs.SourcePolling) and queues it on its printer. Transports call it in the order
+// jobs arrive, so they are queued in that order.
+func (e *Executor) Execute(job IncomingJob, source string) {
+	log.Printf("Received print job via %s: %s for printer: %s", source, job.JobID, job.PrinterID)
+
+	// Redelivered job: re-acknowledge its original outcome instead of printing again
+	if prev, seen := e.spooler.Seen(job.JobID); seen {
+		log.Printf("Duplicate job %s (%s), re-acknowledging", job.JobID, prev.Status)
+		e.report(job.JobID, prev.Status, prev.Error)
+		return
+	}
+
+	escposData, err := e.jobData(job)
+	if err != nil {
+		log.Printf("Failed to prepare job %s: %v", job.JobID, err)
+		e.fail(job.JobID, err.Error())
+		return
+	}
+
+	notBefore, err := jobs.ParseSchedule(job.PrintAt, job.NotBefore)
+	if err != nil {
+		log.Printf("Rejecting job %s: %v", job.JobID, err)
+		e.fail(job.JobID, err.Error())
+		return
+	}
+
+	options := jobs.ParseOptions(job.Options)
+	if e.OnJobReceived != nil {
+		e.OnJobReceived(job.JobID, job.PrinterID, len(escposData), options.CopyCount())
+	}
+
+	// Hand off to the printer's worker; it reports progress through the spooler
+	prev, accepted := e.spooler.Submit(jobs.Job{
+		ID:        job.JobID,
+		PrinterID: job.PrinterID,
+		Data:      escposData,
+		Source:    source,
+		Priority:  job.Priority,
+		Options:   options,
+		CreatedAt: time.Now(),
+		NotBefore: notBefore,
+	})
+	if !accepted {
+		// Delivered again while this one was being prepared: acknowledge the latest status
+		if latest, ok := e.spooler.Seen(job.JobID); ok {
+			prev = latest
+		}
+		log.Printf("Duplicate job %s (%s), re-acknowledging", job.JobID, prev.Status)
+		e.report(job.JobID, prev.Status, prev.Error)
+	}
+}
+
+// jobData returns a job's ESC/POS: the decoded base64 data, or the rendered template
+func (e *Executor) jobData(job IncomingJob) ([]byte, error) {
+	if job.Template != "" {
+		if e.RenderTemplate == nil {
+			return nil, fmt.Errorf("template jobs are not supported")
+		}
+		data, err := e.RenderTemplate(job.PrinterID, job.Template, job.Data, job.Options)
+		if err != nil {
+			return nil, fmt.Errorf("template error: %w", err)
+		}
+		return data, nil
+	}
+
+	var encoded string
+	if len(job.Data) > 0 {
+		if err := json.Unmarshal(job.Data, &encoded); err != nil {
+			return nil, fmt.Errorf("decode error: data must be a base64 string")
+		}
+	}
+	if encoded == "" {
+		return nil, fmt.Errorf("no print data")
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode error: %v", err)
+	}
+	return data, nil
+}
+
+// Cancel cancels a job at the cloud's request. The spooler reports the resulting
+// "cancelled" status; only jobs that can no longer be cancelled are answered here.
+func (e *Executor) Cancel(jobID string) {
+	log.Printf("Cancel requested for job: %s", jobID)
+
+	inFlight, err := e.spooler.Cancel(jobID)
+	if err == nil {
+		if inFlight {
+			log.Printf("Job %s is printing; it will stop after the chunk being written", jobID)
+		}
+		return
+	}
+
+	// Already finished: tell the cloud where it stands
+	if prev, seen := e.spooler.Seen(jobID); seen {
+		e.report(jobID, prev.Status, fmt.Sprintf("cannot cancel: job already %s", prev.Status))
+		return
+	}
+
+	// Not received yet: make sure it is not printed if it arrives later
+	e.spooler.Reject(jobID)
+	e.report(jobID, "cancelled", "")
+}
+
+// SetPaused pauses or resumes a printer at the cloud's request
+func (e *Executor) SetPaused(printerID string, paused bool) {
+	if e.OnPausePrinter == nil {
+		return
+	}
+	if err := e.OnPausePrinter(printerID, paused); err != nil {
+		log.Printf("Failed to pause/resume printer %s: %v", printerID, err)
+	}
+}
+
+// fail reports a job that could not be queued
+func (e *Executor) fail(jobID, errMsg string) {
+	e.report(jobID, "failed", errMsg)
+	if e.OnJobCompleted != nil {
+		e.OnJobCompleted(jobID, "failed", errMsg)
+	}
+}
+
+func (e *Executor) report(jobID, status, errMsg string) {
+	if e.Report != nil {
+		e.Report(jobID, status, errMsg)
+	}
+}
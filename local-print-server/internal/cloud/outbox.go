@@ -0,0 +1,317 @@
+package cloud
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jetsetgo/local-print-server/internal/jobs"
+)
+
+// ErrNotConnected means no cloud transport can take a status update right now
+var ErrNotConnected = errors.New("not connected to cloud")
+
+// ErrRejected means the cloud refused a status update; retrying will not help
+var ErrRejected = errors.New("rejected by cloud")
+
+// maxOutbox bounds the updates kept while the cloud is unreachable
+const maxOutbox = 10000
+
+// outboxCompactAfter is how many log lines beyond the pending updates are allowed
+// before the log is rewritten
+const outboxCompactAfter = 1000
+
+// Outbox retry delays while delivery fails
+const (
+	outboxInitialBackoff = 2 * time.Second
+	outboxMaxBackoff     = 60 * time.Second
+)
+
+// StatusUpdate is a job status report waiting to reach the cloud
+type StatusUpdate struct {
+	Seq    int64     `json:"seq"`
+	JobID  string    `json:"job_id"`
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	At     time.Time `json:"at"`
+
+	Timing *jobs.Timing `json:"timing,omitempty"` // When the job reached each stage, if known
+}
+
+// outboxRecord is a line of the outbox log: a queued update, or Acked, the Seq up to
+// which updates have left the outbox (delivered, rejected or dropped)
+type outboxRecord struct {
+	Update *StatusUpdate `json:"update,omitempty"`
+	Acked  int64         `json:"acked,omitempty"`
+}
+
+// Outbox holds job status updates on disk until the cloud has them, so a network
+// blip or restart does not leave a job stuck in its last reported state. Updates
+// are delivered one at a time, oldest first.
+//
+// On disk the outbox is an append-only JSON-lines log, so queueing or delivering an
+// update writes one line. The log is rewritten with just the pending updates when it
+// empties or has grown outboxCompactAfter lines past them.
+type Outbox struct {
+	path string
+
+	mu       sync.Mutex
+	entries  []StatusUpdate
+	seq      int64
+	log      *os.File
+	logLines int
+
+	wake chan struct{}
+	done chan struct{}
+
+	// Deliver sends an update over the connected transport. Returning an error
+	// keeps it queued, unless the error wraps ErrRejected.
+	Deliver func(StatusUpdate) error
+}
+
+// OpenOutbox loads undelivered updates from path
+func OpenOutbox(path string) (*Outbox, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	o := &Outbox{
+		path: path,
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+
+	if err := o.load(); err != nil {
+		return nil, err
+	}
+	if err := o.compact(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// load replays the log
+func (o *Outbox) load() error {
+	f, err := os.Open(o.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read status outbox: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var rec outboxRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue // Skip a line torn by a crash mid-write
+		}
+		if u := rec.Update; u != nil {
+			o.entries = append(o.entries, *u)
+			o.seq = max(o.seq, u.Seq)
+		}
+		if rec.Acked > 0 {
+			n := 0
+			for n < len(o.entries) && o.entries[n].Seq <= rec.Acked {
+				n++
+			}
+			o.entries = o.entries[n:]
+			o.seq = max(o.seq, rec.Acked)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read status outbox: %w", err)
+	}
+	return nil
+}
+
+// Start begins delivering updates
+func (o *Outbox) Start() {
+	go o.run()
+}
+
+// Stop stops delivery. Undelivered updates stay on disk.
+func (o *Outbox) Stop() {
+	close(o.done)
+	o.mu.Lock()
+	if o.log != nil {
+		o.log.Close()
+		o.log = nil
+	}
+	o.mu.Unlock()
+}
+
+// Add queues a status update for delivery. Seq and At are set here. An update that
+// repeats the job's latest undelivered one (e.g. a re-acknowledged duplicate) is not queued again.
+func (o *Outbox) Add(u StatusUpdate) {
+	o.mu.Lock()
+	for i := len(o.entries) - 1; i >= 0; i-- {
+		if e := o.entries[i]; e.JobID == u.JobID {
+			if e.Status == u.Status && e.Error == u.Error {
+				o.mu.Unlock()
+				o.Flush()
+				return
+			}
+			break
+		}
+	}
+	o.seq++
+	u.Seq = o.seq
+	u.At = time.Now()
+	o.entries = append(o.entries, u)
+	o.append(outboxRecord{Update: &u})
+	if n := len(o.entries) - maxOutbox; n > 0 {
+		log.Printf("Status outbox full, dropping %d oldest update(s)", n)
+		o.ack(o.entries[n-1].Seq)
+	}
+	o.mu.Unlock()
+
+	o.Flush()
+}
+
+// Flush retries delivery now, e.g. after a transport reconnects
+func (o *Outbox) Flush() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Len returns the number of undelivered updates
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+// run delivers updates in order, backing off while the cloud is unreachable
+func (o *Outbox) run() {
+	backoff := outboxInitialBackoff
+	for {
+		var retry <-chan time.Time
+		if !o.deliverAll() {
+			retry = time.After(backoff)
+			backoff *= 2
+			if backoff > outboxMaxBackoff {
+				backoff = outboxMaxBackoff
+			}
+		} else {
+			backoff = outboxInitialBackoff
+		}
+
+		select {
+		case <-o.done:
+			return
+		case <-o.wake:
+		case <-retry:
+		}
+	}
+}
+
+// deliverAll sends queued updates until the outbox is empty or delivery fails.
+// Returns false if updates are left.
+func (o *Outbox) deliverAll() bool {
+	for {
+		o.mu.Lock()
+		if len(o.entries) == 0 {
+			o.mu.Unlock()
+			return true
+		}
+		u := o.entries[0]
+		o.mu.Unlock()
+
+		if o.Deliver == nil {
+			return false
+		}
+		if err := o.Deliver(u); err != nil {
+			if !errors.Is(err, ErrRejected) {
+				return false
+			}
+			log.Printf("Dropping status %q for job %s: %v", u.Status, u.JobID, err)
+		}
+
+		o.mu.Lock()
+		if len(o.entries) > 0 && o.entries[0].Seq == u.Seq {
+			o.ack(u.Seq)
+		}
+		o.mu.Unlock()
+	}
+}
+
+// ack removes the updates up to seq and logs it. Caller must hold mu.
+func (o *Outbox) ack(seq int64) {
+	n := 0
+	for n < len(o.entries) && o.entries[n].Seq <= seq {
+		n++
+	}
+	o.entries = o.entries[n:]
+	if o.log == nil {
+		return // Stopped; the update is sent again after a restart
+	}
+	if len(o.entries) == 0 || o.logLines > len(o.entries)+outboxCompactAfter {
+		if err := o.compact(); err != nil {
+			log.Printf("Failed to compact status outbox: %v", err)
+		}
+		return
+	}
+	o.append(outboxRecord{Acked: seq})
+}
+
+// append writes a record to the log. Caller must hold mu.
+func (o *Outbox) append(rec outboxRecord) {
+	if o.log == nil {
+		return // Stopped
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	if _, err := o.log.Write(append(line, '\n')); err != nil {
+		log.Printf("Failed to save status outbox: %v", err)
+		return
+	}
+	o.logLines++
+}
+
+// compact rewrites the log with only the pending updates (temp file + rename) and
+// reopens it for appending. Caller must hold mu, except while opening.
+func (o *Outbox) compact() error {
+	tmp := o.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to write status outbox: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for i := range o.entries {
+		enc.Encode(outboxRecord{Update: &o.entries[i]})
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write status outbox: %w", err)
+	}
+	f.Close()
+	if err := os.Rename(tmp, o.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write status outbox: %w", err)
+	}
+
+	if o.log != nil {
+		o.log.Close()
+	}
+	o.log, err = os.OpenFile(o.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open status outbox: %w", err)
+	}
+	o.logLines = len(o.entries)
+	return nil
+}
@@ -0,0 +1,144 @@
+package cloud
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jetsetgo/local-print-server/internal/config"
+)
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	n := 0
+	for s := bufio.NewScanner(f); s.Scan(); {
+		n++
+	}
+	return n
+}
+
+func TestOutboxPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	o, err := OpenOutbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		o.Add(StatusUpdate{JobID: fmt.Sprintf("job-%d", i), Status: "completed"})
+	}
+
+	// Deliver two, then fail
+	delivered := 0
+	o.Deliver = func(u StatusUpdate) error {
+		if delivered == 2 {
+			return ErrNotConnected
+		}
+		delivered++
+		return nil
+	}
+	if o.deliverAll() {
+		t.Fatal("deliverAll reported an empty outbox")
+	}
+	if n := countLines(t, path); n != 7 {
+		t.Errorf("log has %d lines, want 5 updates and 2 acks", n)
+	}
+	o.Stop()
+
+	o, err = OpenOutbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+	if o.Len() != 3 || o.entries[0].JobID != "job-2" {
+		t.Fatalf("reopened outbox has %d updates starting %+v, want job-2..job-4", o.Len(), o.entries[0])
+	}
+	if n := countLines(t, path); n != 3 {
+		t.Errorf("log has %d lines after reopening, want it compacted to 3", n)
+	}
+
+	// Emptying the outbox truncates the log; new updates continue the sequence
+	o.Deliver = func(StatusUpdate) error { return nil }
+	if !o.deliverAll() {
+		t.Fatal("outbox not emptied")
+	}
+	if n := countLines(t, path); n != 0 {
+		t.Errorf("log has %d lines once empty, want 0", n)
+	}
+	o.Add(StatusUpdate{JobID: "job-5", Status: "queued"})
+	if u := o.entries[0]; u.Seq != 6 {
+		t.Errorf("seq = %d, want 6", u.Seq)
+	}
+}
+
+func TestOutboxDeliversOverWebSocketWithoutAcks(t *testing.T) {
+	// A cloud that reads status messages and never answers them
+	received := make(chan OutgoingMessage, 10)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var msg OutgoingMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if msg.Type == "status" {
+				received <- msg
+			}
+		}
+	}))
+	defer srv.Close()
+
+	o, err := OpenOutbox(filepath.Join(t.TempDir(), "outbox.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+	for _, status := range []string{"queued", "printing", "completed"} {
+		o.Add(StatusUpdate{JobID: "job-1", Status: status})
+	}
+
+	ws := NewWSClient(&config.CloudConfig{
+		WSEndpoint:       "ws" + strings.TrimPrefix(srv.URL, "http"),
+		WSReconnectDelay: 10 * time.Millisecond,
+		WSMaxReconnect:   10 * time.Millisecond,
+		WSPingInterval:   time.Minute,
+	}, nil, Hooks{OnConnected: o.Flush})
+	o.Deliver = ws.DeliverStatus
+	ws.Start()
+	defer ws.Stop()
+	o.Start()
+
+	for _, want := range []string{"queued", "printing", "completed"} {
+		select {
+		case msg := <-received:
+			if msg.Status != want {
+				t.Fatalf("got status %s, want %s", msg.Status, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("status %s never reached the cloud", want)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for o.Len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if o.Len() != 0 {
+		t.Errorf("%d updates still pending, want the outbox emptied without acks", o.Len())
+	}
+}
@@ -1,8 +1,6 @@
 package cloud
 
 import (
-	"bytes"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,42 +10,32 @@ import (
 	"time"
 
 	"github.com/jetsetgo/local-print-server/internal/config"
-	"github.com/jetsetgo/local-print-server/internal/printer"
+	"github.com/jetsetgo/local-print-server/internal/jobs"
 )
 
 // PollClient polls the cloud for pending print jobs and sends heartbeats
 type PollClient struct {
-	config     *config.CloudConfig
-	printerMgr *printer.Manager
-	client     *http.Client
-	mu         sync.Mutex
+	config *config.CloudConfig
+	exec   *Executor
+	hooks  Hooks
+	client *http.Client
+	mu     sync.Mutex
 
 	connected bool
 	lastError error
 	lastSeen  time.Time
 
 	done chan struct{}
-
-	// Callback for job tracking
-	OnJobReceived  func(jobID, printerID string, dataSize int)
-	OnJobCompleted func(jobID, status, errMsg string)
-
-	// PrinterStatuses returns current printer statuses for heartbeat
-	PrinterStatuses func() map[string]string
-
-	// PrinterList returns printer configs for syncing to cloud
-	PrinterList func() []map[string]interface{}
-
-	printersSynced bool
 }
 
-// NewPollClient creates a new polling client
-func NewPollClient(cfg *config.CloudConfig, printerMgr *printer.Manager) *PollClient {
+// NewPollClient creates a new polling client that passes received jobs to exec
+func NewPollClient(cfg *config.CloudConfig, exec *Executor, hooks Hooks) *PollClient {
 	return &PollClient{
-		config:     cfg,
-		printerMgr: printerMgr,
-		client:     &http.Client{Timeout: 15 * time.Second},
-		done:       make(chan struct{}),
+		config: cfg,
+		exec:   exec,
+		hooks:  hooks,
+		client: &http.Client{Timeout: 15 * time.Second},
+		done:   make(chan struct{}),
 	}
 }
 
@@ -72,6 +60,7 @@ func (p *PollClient) Status() ConnectionStatus {
 	}
 
 	return ConnectionStatus{
+		Transport:    TransportPolling,
 		Connected:    p.connected,
 		Reconnecting: false,
 		LastError:    errStr,
@@ -81,7 +70,7 @@ func (p *PollClient) Status() ConnectionStatus {
 
 func (p *PollClient) pollLoop() {
 	// Sync printers, send heartbeat, and poll immediately on start
-	p.syncPrinters()
+	syncPrinters(p.config, p.client, p.hooks.PrinterList)
 	p.sendHeartbeat()
 	p.poll()
 
@@ -105,30 +94,17 @@ func (p *PollClient) pollLoop() {
 	}
 }
 
-type pollJob struct {
-	JobID     string `json:"job_id"`
-	PrinterID string `json:"printer_id"`
-	Data      string `json:"data"`
-}
-
 type pollJobResponse struct {
-	Jobs []pollJob `json:"jobs"`
+	Jobs []IncomingJob `json:"jobs"`
 }
 
 func (p *PollClient) poll() {
-	url := fmt.Sprintf("%s/servers/%s/jobs", p.config.Endpoint, p.config.ServerID)
-
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := newRequest(p.config, "GET", "/jobs", nil)
 	if err != nil {
 		p.setError(err)
 		return
 	}
 
-	req.Header.Set("X-API-Key", p.config.APIKey)
-	if p.config.Tenant != "" {
-		req.Header.Set("X-DB-Name", p.config.Tenant)
-	}
-
 	resp, err := p.client.Do(req)
 	if err != nil {
 		p.setError(err)
@@ -144,109 +120,91 @@ func (p *PollClient) poll() {
 
 	// Successfully reached cloud
 	p.mu.Lock()
+	wasConnected := p.connected
 	p.connected = true
 	p.lastError = nil
 	p.lastSeen = time.Now()
 	p.mu.Unlock()
 
+	if !wasConnected && p.hooks.OnConnected != nil {
+		p.hooks.OnConnected()
+	}
+
 	var result pollJobResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		log.Printf("Failed to parse poll response: %v", err)
 		return
 	}
 
+	// The cloud keeps returning a job until it has recorded our status update; the
+	// executor re-acknowledges those instead of printing again. Jobs are queued in
+	// the order the cloud returned them.
 	for _, job := range result.Jobs {
-		if job.Data == "" {
-			log.Printf("Skipping job %s: no data", job.JobID)
-			continue
-		}
-		go p.processJob(job.JobID, job.PrinterID, job.Data)
+		p.exec.Execute(job, jobs.SourcePolling)
 	}
 }
 
-func (p *PollClient) processJob(jobID, printerID, data string) {
-	log.Printf("Received print job via polling: %s for printer: %s", jobID, printerID)
-
-	escposData, err := base64.StdEncoding.DecodeString(data)
-	if err != nil {
-		log.Printf("Failed to decode job data: %v", err)
-		p.reportStatus(jobID, "failed", fmt.Sprintf("decode error: %v", err))
-		return
+// SendStatus sends a job status update once
+func (p *PollClient) SendStatus(jobID, status, errMsg string) {
+	if err := p.DeliverStatus(StatusUpdate{JobID: jobID, Status: status, Error: errMsg}); err != nil {
+		log.Printf("Failed to report job status: %v", err)
 	}
+}
 
-	if p.OnJobReceived != nil {
-		p.OnJobReceived(jobID, printerID, len(escposData))
-	}
+// SendPrinterStatus does nothing: printer statuses go with every heartbeat
+func (p *PollClient) SendPrinterStatus(status PrinterStatus) {}
 
-	err = p.printerMgr.Print(printerID, escposData)
-	if err != nil {
-		log.Printf("Print failed: %v", err)
-		p.reportStatus(jobID, "failed", err.Error())
-		if p.OnJobCompleted != nil {
-			p.OnJobCompleted(jobID, "failed", err.Error())
-		}
-		return
+// DeliverStatus PATCHes a job status update to the cloud. 4xx responses other than
+// 408 and 429 are reported as ErrRejected.
+func (p *PollClient) DeliverStatus(u StatusUpdate) error {
+	body := map[string]interface{}{
+		"status": u.Status,
+		"error":  u.Error,
 	}
-
-	log.Printf("Print job %s completed successfully", jobID)
-	p.reportStatus(jobID, "completed", "")
-	if p.OnJobCompleted != nil {
-		p.OnJobCompleted(jobID, "completed", "")
+	if !u.At.IsZero() {
+		body["at"] = u.At
 	}
-}
-
-func (p *PollClient) reportStatus(jobID, status, errMsg string) {
-	url := fmt.Sprintf("%s/servers/%s/jobs/%s", p.config.Endpoint, p.config.ServerID, jobID)
-
-	payload, _ := json.Marshal(map[string]string{
-		"status": status,
-		"error":  errMsg,
-	})
-
-	req, err := http.NewRequest("PATCH", url, bytes.NewReader(payload))
-	if err != nil {
-		log.Printf("Failed to create status request: %v", err)
-		return
+	if u.Timing != nil {
+		body["timing"] = u.Timing
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", p.config.APIKey)
-	if p.config.Tenant != "" {
-		req.Header.Set("X-DB-Name", p.config.Tenant)
+	req, err := newRequest(p.config, "PATCH", "/jobs/"+u.JobID, body)
+	if err != nil {
+		return fmt.Errorf("failed to create status request: %w", err)
 	}
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		log.Printf("Failed to report job status: %v", err)
-		return
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%w: status update returned %d: %s", ErrRejected, resp.StatusCode, string(body))
+	default:
+		return fmt.Errorf("status update returned %d", resp.StatusCode)
 	}
-	resp.Body.Close()
 }
 
 func (p *PollClient) sendHeartbeat() {
-	url := fmt.Sprintf("%s/servers/%s/heartbeat", p.config.Endpoint, p.config.ServerID)
-
 	printers := map[string]string{}
-	if p.PrinterStatuses != nil {
-		printers = p.PrinterStatuses()
+	if p.hooks.PrinterSnapshot != nil {
+		for _, ps := range p.hooks.PrinterSnapshot() {
+			printers[ps.PrinterID] = ps.Status
+		}
 	}
 
-	payload, _ := json.Marshal(map[string]interface{}{
+	req, err := newRequest(p.config, "POST", "/heartbeat", map[string]interface{}{
 		"printers": printers,
 	})
-
-	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
 	if err != nil {
 		log.Printf("Failed to create heartbeat request: %v", err)
 		return
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", p.config.APIKey)
-	if p.config.Tenant != "" {
-		req.Header.Set("X-DB-Name", p.config.Tenant)
-	}
-
 	resp, err := p.client.Do(req)
 	if err != nil {
 		p.setError(err)
@@ -268,56 +226,9 @@ func (p *PollClient) sendHeartbeat() {
 	p.mu.Unlock()
 }
 
-// SyncPrinters forces a printer sync on next opportunity
+// SyncPrinters forces a printer sync
 func (p *PollClient) SyncPrinters() {
-	p.mu.Lock()
-	p.printersSynced = false
-	p.mu.Unlock()
-	go p.syncPrinters()
-}
-
-func (p *PollClient) syncPrinters() {
-	if p.PrinterList == nil {
-		return
-	}
-
-	printers := p.PrinterList()
-	url := fmt.Sprintf("%s/servers/%s/printers", p.config.Endpoint, p.config.ServerID)
-
-	payload, _ := json.Marshal(map[string]interface{}{
-		"printers": printers,
-	})
-
-	req, err := http.NewRequest("PUT", url, bytes.NewReader(payload))
-	if err != nil {
-		log.Printf("Failed to create printer sync request: %v", err)
-		return
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", p.config.APIKey)
-	if p.config.Tenant != "" {
-		req.Header.Set("X-DB-Name", p.config.Tenant)
-	}
-
-	resp, err := p.client.Do(req)
-	if err != nil {
-		log.Printf("Failed to sync printers: %v", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Printer sync returned %d: %s", resp.StatusCode, string(body))
-		return
-	}
-
-	p.mu.Lock()
-	p.printersSynced = true
-	p.mu.Unlock()
-
-	log.Printf("Printers synced to cloud (%d printers)", len(printers))
+	go syncPrinters(p.config, p.client, p.hooks.PrinterList)
 }
 
 func (p *PollClient) setError(err error) {
@@ -0,0 +1,79 @@
+package cloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/jetsetgo/local-print-server/internal/config"
+)
+
+// authHeader returns the headers that identify this server to the cloud
+func authHeader(cfg *config.CloudConfig) http.Header {
+	header := http.Header{}
+	if cfg.APIKey != "" {
+		header.Set("X-API-Key", cfg.APIKey)
+	}
+	if cfg.Tenant != "" {
+		header.Set("X-DB-Name", cfg.Tenant)
+	}
+	return header
+}
+
+// newRequest creates an authenticated request for a path of this server's cloud API,
+// e.g. "/jobs". A non-nil body is sent as JSON.
+func newRequest(cfg *config.CloudConfig, method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	url := fmt.Sprintf("%s/servers/%s%s", cfg.Endpoint, cfg.ServerID, path)
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = authHeader(cfg)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// syncPrinters PUTs the printer list to the cloud
+func syncPrinters(cfg *config.CloudConfig, client *http.Client, printerList func() []map[string]interface{}) {
+	if printerList == nil {
+		return
+	}
+
+	printers := printerList()
+	req, err := newRequest(cfg, "PUT", "/printers", map[string]interface{}{
+		"printers": printers,
+	})
+	if err != nil {
+		log.Printf("Failed to create printer sync request: %v", err)
+		return
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("Failed to sync printers: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Printf("Printer sync returned %d: %s", resp.StatusCode, string(body))
+		return
+	}
+
+	log.Printf("Printers synced to cloud (%d printers)", len(printers))
+}
@@ -0,0 +1,222 @@
+package cloud
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jetsetgo/local-print-server/internal/config"
+)
+
+// wsStableAfter is how long the WebSocket must stay connected before polling stops and
+// its failure count is reset, so a connection that drops straight away doesn't flap
+// between transports
+const wsStableAfter = 30 * time.Second
+
+// Supervisor runs the WebSocket as the primary transport and falls back to HTTP polling
+// while it keeps failing. The WebSocket keeps reconnecting in the background, and once
+// it has stayed connected for wsStableAfter, polling stops again. Jobs that arrive over
+// both while they overlap are dropped as duplicates by the spooler.
+type Supervisor struct {
+	config        *config.CloudConfig
+	exec          *Executor
+	hooks         Hooks
+	ws            *WSClient
+	fallbackAfter int
+
+	mu       sync.Mutex
+	poll     *PollClient // Running fallback; nil while on the WebSocket
+	failures int
+	reason   string
+	since    time.Time
+	conns    int // Connections made, so a stability check can tell if it is stale
+	stopped  bool
+}
+
+// NewSupervisor creates a supervised WebSocket client. Polling starts after
+// cfg.WSFallbackAfter consecutive WebSocket failures; 0 never falls back. An
+// authentication failure is not a reason to poll, since polling uses the same key.
+func NewSupervisor(cfg *config.CloudConfig, exec *Executor, hooks Hooks) *Supervisor {
+	s := &Supervisor{
+		config:        cfg,
+		exec:          exec,
+		hooks:         hooks,
+		fallbackAfter: cfg.WSFallbackAfter,
+		reason:        "websocket is the primary transport",
+		since:         time.Now(),
+	}
+
+	wsHooks := hooks
+	wsHooks.OnConnected = func() {
+		if hooks.OnConnected != nil {
+			hooks.OnConnected()
+		}
+		s.wsConnected()
+	}
+	s.ws = NewWSClient(cfg, exec, wsHooks)
+	s.ws.onDisconnected = s.wsFailed
+	return s
+}
+
+// Start connects the WebSocket
+func (s *Supervisor) Start() {
+	s.ws.Start()
+}
+
+// Stop closes the WebSocket and stops any polling
+func (s *Supervisor) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	poll := s.poll
+	s.poll = nil
+	s.mu.Unlock()
+
+	s.ws.Stop()
+	if poll != nil {
+		poll.Stop()
+	}
+}
+
+// Status returns the state of the transport in use, why it is in use, and the
+// WebSocket's own state
+func (s *Supervisor) Status() ConnectionStatus {
+	s.mu.Lock()
+	reason, since, failures := s.reason, s.since, s.failures
+	poll := s.poll
+	s.mu.Unlock()
+
+	ws := s.ws.Status()
+	st := ws
+	if poll != nil {
+		st = poll.Status()
+	}
+	st.Reason = reason
+	st.Since = since
+	st.WSFailures = failures
+	st.WebSocket = &ws
+	return st
+}
+
+// polling returns the running polling client, or nil while on the WebSocket
+func (s *Supervisor) polling() *PollClient {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.poll
+}
+
+// SendStatus sends a job status update over the active transport
+func (s *Supervisor) SendStatus(jobID, status, errMsg string) {
+	if poll := s.polling(); poll != nil {
+		poll.SendStatus(jobID, status, errMsg)
+		return
+	}
+	s.ws.SendStatus(jobID, status, errMsg)
+}
+
+// DeliverStatus delivers an outbox update over the active transport
+func (s *Supervisor) DeliverStatus(u StatusUpdate) error {
+	if poll := s.polling(); poll != nil {
+		return poll.DeliverStatus(u)
+	}
+	return s.ws.DeliverStatus(u)
+}
+
+// SendPrinterStatus pushes a printer status change over the WebSocket. While polling,
+// the heartbeat carries printer statuses instead.
+func (s *Supervisor) SendPrinterStatus(status PrinterStatus) {
+	s.ws.SendPrinterStatus(status)
+}
+
+// SyncPrinters forces a printer sync over the active transport
+func (s *Supervisor) SyncPrinters() {
+	if poll := s.polling(); poll != nil {
+		poll.SyncPrinters()
+		return
+	}
+	s.ws.SyncPrinters()
+}
+
+// wsFailed counts a WebSocket failure and starts polling once there are enough
+func (s *Supervisor) wsFailed(closeCode int, err error) {
+	if closeCode == CloseReplaced {
+		return // A newer connection took over; the WebSocket itself works
+	}
+
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.failures++
+	if closeCode == CloseAuthFailure {
+		// The WebSocket gives up; report why rather than poll with a key that was refused
+		s.reason = fmt.Sprintf("websocket authentication failed, not retrying: %v", err)
+		s.since = time.Now()
+		reason, poll := s.reason, s.poll
+		s.poll = nil
+		s.mu.Unlock()
+
+		if poll != nil {
+			poll.Stop()
+		}
+		log.Printf("Cloud transport stopped: %s", reason)
+		return
+	}
+	if s.poll != nil || s.fallbackAfter <= 0 || s.failures < s.fallbackAfter {
+		s.mu.Unlock()
+		return
+	}
+
+	s.reason = fmt.Sprintf("websocket failed %d times in a row, last: %v", s.failures, err)
+	s.since = time.Now()
+	s.poll = NewPollClient(s.config, s.exec, s.hooks)
+	s.poll.Start()
+	reason := s.reason
+	s.mu.Unlock()
+
+	log.Printf("Falling back to HTTP polling: %s", reason)
+	if s.hooks.OnSwitch != nil {
+		s.hooks.OnSwitch(TransportPolling, reason)
+	}
+}
+
+// wsConnected schedules a check that the new connection is stable
+func (s *Supervisor) wsConnected() {
+	s.mu.Lock()
+	s.conns++
+	conn := s.conns
+	s.mu.Unlock()
+
+	time.AfterFunc(wsStableAfter, func() { s.wsStable(conn) })
+}
+
+// wsStable resets the failure count and stops polling if connection conn is still up
+func (s *Supervisor) wsStable(conn int) {
+	if !s.ws.Status().Connected {
+		return
+	}
+
+	s.mu.Lock()
+	if s.stopped || conn != s.conns {
+		s.mu.Unlock()
+		return
+	}
+	s.failures = 0
+	poll := s.poll
+	if poll == nil {
+		s.mu.Unlock()
+		return
+	}
+	s.poll = nil
+	s.reason = fmt.Sprintf("websocket reconnected and stable for %v", wsStableAfter)
+	s.since = time.Now()
+	reason := s.reason
+	s.mu.Unlock()
+
+	poll.Stop()
+	log.Printf("Switched back to WebSocket: %s", reason)
+	if s.hooks.OnSwitch != nil {
+		s.hooks.OnSwitch(TransportWebSocket, reason)
+	}
+}
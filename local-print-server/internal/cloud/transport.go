@@ -0,0 +1,55 @@
+package cloud
+
+import "github.com/jetsetgo/local-print-server/internal/config"
+
+// Transports
+const (
+	TransportWebSocket = "websocket"
+	TransportPolling   = "polling"
+)
+
+// Transport is a connection to the cloud. Jobs and commands it receives are passed to
+// the Executor it was created with; status updates travel back over it.
+type Transport interface {
+	Start()
+	Stop()
+	Status() ConnectionStatus
+
+	// SyncPrinters sends the printer list to the cloud
+	SyncPrinters()
+
+	// SendStatus sends a job status update once, bypassing the outbox
+	SendStatus(jobID, status, errMsg string)
+
+	// DeliverStatus sends an outbox update and waits for the outcome. Returns
+	// ErrNotConnected while the cloud can't be reached.
+	DeliverStatus(u StatusUpdate) error
+
+	// SendPrinterStatus pushes a printer status change, if the transport can
+	SendPrinterStatus(status PrinterStatus)
+}
+
+// Hooks connect a transport to the rest of the server. Nil hooks are skipped.
+type Hooks struct {
+	// PrinterList returns printer configs for syncing to the cloud
+	PrinterList func() []map[string]interface{}
+
+	// PrinterSnapshot returns the current status of every printer, sent when the
+	// WebSocket connects and with every polling heartbeat
+	PrinterSnapshot func() []PrinterStatus
+
+	// OnConnected is called each time the cloud becomes reachable
+	OnConnected func()
+
+	// OnSwitch is called when a Supervisor changes the transport in use
+	OnSwitch func(transport, reason string)
+}
+
+// NewTransport creates the transport the configuration asks for: the WebSocket,
+// supervised to fall back to polling, or polling alone
+func NewTransport(cfg *config.CloudConfig, exec *Executor, hooks Hooks) Transport {
+	if cfg.UseWebSocket {
+		return NewSupervisor(cfg, exec, hooks)
+	}
+	return NewPollClient(cfg, exec, hooks)
+}
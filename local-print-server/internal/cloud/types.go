@@ -4,8 +4,24 @@ import "time"
 
 // ConnectionStatus represents the cloud connection status
 type ConnectionStatus struct {
+	Transport    string // TransportWebSocket or TransportPolling: the transport in use
 	Connected    bool
 	Reconnecting bool
 	LastError    string
 	LastSeen     time.Time
+
+	// Set by a Supervisor: why its transport is in use and since when, and the
+	// WebSocket's own state, which differs while polling stands in for it
+	Reason     string
+	Since      time.Time
+	WSFailures int // Consecutive WebSocket failures
+	WebSocket  *ConnectionStatus
+}
+
+// PrinterStatus represents a printer's state as reported to the cloud
+type PrinterStatus struct {
+	PrinterID string    `json:"printer_id"`
+	Status    string    `json:"status"`            // online, offline, paper_out, cover_open, paper_low, error, unknown
+	Reasons   []string  `json:"reasons,omitempty"` // Every condition detected, e.g. paper_out and cover_open
+	CheckedAt time.Time `json:"checked_at"`
 }
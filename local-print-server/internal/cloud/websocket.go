@@ -1,11 +1,8 @@
 package cloud
 
 import (
-	"bytes"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"sync"
@@ -13,7 +10,7 @@ import (
 
 	"github.com/gorilla/websocket"
 	"github.com/jetsetgo/local-print-server/internal/config"
-	"github.com/jetsetgo/local-print-server/internal/printer"
+	"github.com/jetsetgo/local-print-server/internal/jobs"
 )
 
 // Cloud WebSocket close codes
@@ -26,10 +23,12 @@ const (
 
 // WSClient manages the WebSocket connection to the cloud server
 type WSClient struct {
-	config     *config.CloudConfig
-	printerMgr *printer.Manager
-	conn       *websocket.Conn
-	mu         sync.Mutex
+	config *config.CloudConfig
+	exec   *Executor
+	hooks  Hooks
+	client *http.Client // For printer syncs
+	conn   *websocket.Conn
+	mu     sync.Mutex
 
 	// State
 	connected    bool
@@ -38,25 +37,26 @@ type WSClient struct {
 	lastSeen     time.Time
 
 	// Channels
-	done chan struct{}
-	send chan []byte
+	done       chan struct{}
+	send       chan []byte
+	sendDirect chan wsWrite // Writes whose caller waits for the outcome
 
-	// Callback for job tracking
-	OnJobReceived  func(jobID, printerID string, dataSize int)
-	OnJobCompleted func(jobID, status, errMsg string)
+	// onDisconnected is called each time a connection attempt fails or an established
+	// connection ends, with the close code (0 if unknown)
+	onDisconnected func(closeCode int, err error)
+}
 
-	// PrinterList returns printer configs for syncing to cloud
-	PrinterList func() []map[string]interface{}
+// wsWrite is a message the write loop reports back on once written
+type wsWrite struct {
+	data   []byte
+	result chan error
 }
 
-// IncomingMessage represents messages from the cloud
+// IncomingMessage represents messages from the cloud. Job, cancel, pause and resume
+// messages carry their fields in the embedded IncomingJob.
 type IncomingMessage struct {
-	Type      string                 `json:"type"`
-	JobID     string                 `json:"job_id,omitempty"`
-	PrinterID string                 `json:"printer_id,omitempty"`
-	Priority  int                    `json:"priority,omitempty"`
-	Data      string                 `json:"data,omitempty"`
-	Options   map[string]interface{} `json:"options,omitempty"`
+	Type string `json:"type"`
+	IncomingJob
 }
 
 // OutgoingMessage represents messages to the cloud
@@ -65,15 +65,29 @@ type OutgoingMessage struct {
 	JobID  string `json:"job_id,omitempty"`
 	Status string `json:"status,omitempty"`
 	Error  string `json:"error,omitempty"`
+
+	// status messages from the outbox: a sequence number that lets the cloud drop an
+	// update it already has, when the status changed and the job's stage times
+	Seq    int64        `json:"seq,omitempty"`
+	At     *time.Time   `json:"at,omitempty"`
+	Timing *jobs.Timing `json:"timing,omitempty"`
+
+	// printer_status messages
+	Printers []PrinterStatus `json:"printers,omitempty"`
+	Snapshot bool            `json:"snapshot,omitempty"` // True if Printers lists every printer
 }
 
-// NewWSClient creates a new WebSocket client
-func NewWSClient(cfg *config.CloudConfig, printerMgr *printer.Manager) *WSClient {
+// NewWSClient creates a new WebSocket client that passes received jobs to exec
+func NewWSClient(cfg *config.CloudConfig, exec *Executor, hooks Hooks) *WSClient {
 	return &WSClient{
-		config:     cfg,
-		printerMgr: printerMgr,
-		done:       make(chan struct{}),
-		send:       make(chan []byte, 10),
+		config: cfg,
+		exec:   exec,
+		hooks:  hooks,
+		client: &http.Client{Timeout: 15 * time.Second},
+		done:   make(chan struct{}),
+		send:   make(chan []byte, 10),
+
+		sendDirect: make(chan wsWrite),
 	}
 }
 
@@ -105,6 +119,7 @@ func (c *WSClient) Status() ConnectionStatus {
 	}
 
 	return ConnectionStatus{
+		Transport:    TransportWebSocket,
 		Connected:    c.connected,
 		Reconnecting: c.reconnecting,
 		LastError:    errStr,
@@ -114,48 +129,7 @@ func (c *WSClient) Status() ConnectionStatus {
 
 // SyncPrinters forces a printer sync via HTTP
 func (c *WSClient) SyncPrinters() {
-	go c.syncPrinters()
-}
-
-func (c *WSClient) syncPrinters() {
-	if c.PrinterList == nil {
-		return
-	}
-
-	printers := c.PrinterList()
-	url := fmt.Sprintf("%s/servers/%s/printers", c.config.Endpoint, c.config.ServerID)
-
-	payload, _ := json.Marshal(map[string]interface{}{
-		"printers": printers,
-	})
-
-	req, err := http.NewRequest("PUT", url, bytes.NewReader(payload))
-	if err != nil {
-		log.Printf("Failed to create printer sync request: %v", err)
-		return
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", c.config.APIKey)
-	if c.config.Tenant != "" {
-		req.Header.Set("X-DB-Name", c.config.Tenant)
-	}
-
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("Failed to sync printers: %v", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Printer sync returned %d: %s", resp.StatusCode, string(body))
-		return
-	}
-
-	log.Printf("Printers synced to cloud (%d printers)", len(printers))
+	go syncPrinters(c.config, c.client, c.hooks.PrinterList)
 }
 
 // connectionLoop manages connection and reconnection
@@ -177,6 +151,10 @@ func (c *WSClient) connectionLoop() {
 			c.lastError = err
 			c.mu.Unlock()
 
+			if c.onDisconnected != nil {
+				c.onDisconnected(closeCode, err)
+			}
+
 			// Handle specific close codes
 			switch closeCode {
 			case CloseAuthFailure:
@@ -227,14 +205,7 @@ func (c *WSClient) connectionLoop() {
 // Returns the close code (0 if unknown) and error.
 func (c *WSClient) connectAndRun() (int, error) {
 	wsURL := c.config.WSEndpoint
-
-	header := http.Header{}
-	if c.config.APIKey != "" {
-		header.Set("X-API-Key", c.config.APIKey)
-	}
-	if c.config.Tenant != "" {
-		header.Set("X-DB-Name", c.config.Tenant)
-	}
+	header := authHeader(c.config)
 
 	log.Printf("Connecting to WebSocket: %s", wsURL)
 
@@ -267,7 +238,16 @@ func (c *WSClient) connectAndRun() (int, error) {
 	log.Println("WebSocket connected")
 
 	// Sync printers on connect (via HTTP)
-	go c.syncPrinters()
+	c.SyncPrinters()
+
+	// Report every printer's current state so the cloud starts from a full picture
+	if c.hooks.PrinterSnapshot != nil {
+		c.sendPrinterStatus(c.hooks.PrinterSnapshot(), true)
+	}
+
+	if c.hooks.OnConnected != nil {
+		c.hooks.OnConnected()
+	}
 
 	// Run read/write loops until disconnection
 	closeCode := c.runConnection()
@@ -353,6 +333,24 @@ func (c *WSClient) runConnection() int {
 				return 0
 			}
 
+		case w := <-c.sendDirect:
+			c.mu.Lock()
+			conn := c.conn
+			c.mu.Unlock()
+
+			if conn == nil {
+				w.result <- ErrNotConnected
+				return 0
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			err := conn.WriteMessage(websocket.TextMessage, w.data)
+			w.result <- err
+			if err != nil {
+				log.Printf("WebSocket write error: %v", err)
+				return 0
+			}
+
 		case <-ticker.C:
 			c.mu.Lock()
 			conn := c.conn
@@ -391,7 +389,14 @@ func (c *WSClient) handleMessage(data []byte) {
 
 	switch msg.Type {
 	case "job":
-		go c.handleJob(msg)
+		// Runs on the read loop, so jobs are queued in the order they arrive
+		c.exec.Execute(msg.IncomingJob, jobs.SourceWebSocket)
+	case "cancel":
+		c.exec.Cancel(msg.JobID)
+	case "pause", "resume":
+		log.Printf("Received %s command via WebSocket for printer: %s", msg.Type, msg.PrinterID)
+		// The new state reaches the cloud as a printer_status message
+		c.exec.SetPaused(msg.PrinterID, msg.Type == "pause")
 	case "pong":
 		// Heartbeat response - connection is alive
 	default:
@@ -399,75 +404,97 @@ func (c *WSClient) handleMessage(data []byte) {
 	}
 }
 
-// handleJob processes an incoming print job
-func (c *WSClient) handleJob(msg IncomingMessage) {
-	log.Printf("Received print job via WebSocket: %s for printer: %s", msg.JobID, msg.PrinterID)
-
-	if msg.Data == "" {
-		log.Printf("Skipping job %s: no data", msg.JobID)
-		c.sendStatus(msg.JobID, "failed", "no print data")
-		if c.OnJobCompleted != nil {
-			c.OnJobCompleted(msg.JobID, "failed", "no print data")
-		}
-		return
-	}
-
-	// Decode base64 ESC/POS data
-	escposData, err := base64.StdEncoding.DecodeString(msg.Data)
+// SendStatus sends a job status update once; it is dropped if the send queue is full
+func (c *WSClient) SendStatus(jobID, status, errMsg string) {
+	data, err := json.Marshal(OutgoingMessage{
+		Type:   "status",
+		JobID:  jobID,
+		Status: status,
+		Error:  errMsg,
+	})
 	if err != nil {
-		log.Printf("Failed to decode job data: %v", err)
-		c.sendStatus(msg.JobID, "failed", fmt.Sprintf("decode error: %v", err))
-		if c.OnJobCompleted != nil {
-			c.OnJobCompleted(msg.JobID, "failed", fmt.Sprintf("decode error: %v", err))
-		}
+		log.Printf("Failed to marshal status message: %v", err)
 		return
 	}
 
-	if c.OnJobReceived != nil {
-		c.OnJobReceived(msg.JobID, msg.PrinterID, len(escposData))
+	select {
+	case c.send <- data:
+	default:
+		log.Println("WebSocket send channel full, dropping status message")
 	}
+}
 
-	// Report "printing" status
-	c.sendStatus(msg.JobID, "printing", "")
+// DeliverStatus sends a job status update, which counts as delivered once written to
+// the connection. Returns ErrNotConnected while the connection is down.
+func (c *WSClient) DeliverStatus(u StatusUpdate) error {
+	c.mu.Lock()
+	connected := c.connected
+	c.mu.Unlock()
+	if !connected {
+		return ErrNotConnected
+	}
 
-	// Send to printer
-	err = c.printerMgr.Print(msg.PrinterID, escposData)
+	msg := OutgoingMessage{
+		Type:   "status",
+		JobID:  u.JobID,
+		Seq:    u.Seq,
+		Status: u.Status,
+		Error:  u.Error,
+		Timing: u.Timing,
+	}
+	if !u.At.IsZero() {
+		msg.At = &u.At
+	}
+	data, err := json.Marshal(msg)
 	if err != nil {
-		log.Printf("Print failed: %v", err)
-		c.sendStatus(msg.JobID, "failed", err.Error())
-		if c.OnJobCompleted != nil {
-			c.OnJobCompleted(msg.JobID, "failed", err.Error())
-		}
-		return
+		return fmt.Errorf("%w: %v", ErrRejected, err)
 	}
 
-	log.Printf("Print job %s completed successfully", msg.JobID)
-	c.sendStatus(msg.JobID, "completed", "")
-	if c.OnJobCompleted != nil {
-		c.OnJobCompleted(msg.JobID, "completed", "")
+	w := wsWrite{data: data, result: make(chan error, 1)}
+	timeout := time.After(15 * time.Second)
+	select {
+	case c.sendDirect <- w:
+	case <-c.done:
+		return ErrNotConnected
+	case <-timeout:
+		return ErrNotConnected
+	}
+	select {
+	case err := <-w.result:
+		return err
+	case <-timeout:
+		return ErrNotConnected
 	}
 }
 
-// sendStatus sends a job status update via WebSocket
-func (c *WSClient) sendStatus(jobID, status, errMsg string) {
-	msg := OutgoingMessage{
-		Type:   "status",
-		JobID:  jobID,
-		Status: status,
-	}
-	if errMsg != "" {
-		msg.Error = errMsg
+// SendPrinterStatus reports a printer status change to the cloud.
+// Changes while disconnected are not queued; the snapshot on reconnect covers them.
+func (c *WSClient) SendPrinterStatus(status PrinterStatus) {
+	c.mu.Lock()
+	connected := c.connected
+	c.mu.Unlock()
+
+	if !connected {
+		return
 	}
+	c.sendPrinterStatus([]PrinterStatus{status}, false)
+}
 
-	data, err := json.Marshal(msg)
+// sendPrinterStatus queues a printer_status message
+func (c *WSClient) sendPrinterStatus(printers []PrinterStatus, snapshot bool) {
+	data, err := json.Marshal(OutgoingMessage{
+		Type:     "printer_status",
+		Printers: printers,
+		Snapshot: snapshot,
+	})
 	if err != nil {
-		log.Printf("Failed to marshal status message: %v", err)
+		log.Printf("Failed to marshal printer status message: %v", err)
 		return
 	}
 
 	select {
 	case c.send <- data:
 	default:
-		log.Println("WebSocket send channel full, dropping status message")
+		log.Println("WebSocket send channel full, dropping printer status message")
 	}
 }
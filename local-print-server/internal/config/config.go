@@ -11,6 +11,7 @@ import (
 type Config struct {
 	Server   ServerConfig    `yaml:"server"`
 	Cloud    CloudConfig     `yaml:"cloud"`
+	Jobs     JobsConfig      `yaml:"jobs"`
 	Printers []PrinterConfig `yaml:"printers"`
 
 	// ConfigPath is the path to the config file (not serialized)
@@ -21,6 +22,12 @@ type Config struct {
 type ServerConfig struct {
 	Port int    `yaml:"port"`
 	Host string `yaml:"host"`
+
+	// How often pinned printers are checked for address changes
+	ResolveInterval time.Duration `yaml:"resolve_interval"`
+
+	// How often each printer's status is probed in the background
+	HealthInterval time.Duration `yaml:"health_interval"`
 }
 
 // CloudConfig represents the cloud server connection configuration
@@ -41,35 +48,119 @@ type CloudConfig struct {
 	WSReconnectDelay time.Duration `yaml:"ws_reconnect_delay"`
 	WSMaxReconnect   time.Duration `yaml:"ws_max_reconnect_delay"`
 	WSPingInterval   time.Duration `yaml:"ws_ping_interval"`
+
+	// Poll over HTTP after this many consecutive WebSocket failures, until the WebSocket
+	// is back; 0 never falls back
+	WSFallbackAfter int `yaml:"ws_fallback_after"`
+}
+
+// JobsConfig represents local job handling configuration
+type JobsConfig struct {
+	// Directory for persistent job data (queue, history)
+	DataDir string `yaml:"data_dir"`
+
+	// How long a job may wait in the offline queue before a warning is logged
+	QueueRetention time.Duration `yaml:"queue_retention"`
+
+	// How often queued jobs are retried
+	ReplayInterval time.Duration `yaml:"replay_interval"`
+
+	// Finished jobs kept in the searchable job history, by count, age and total size
+	HistorySize     int           `yaml:"history_size"`
+	HistoryMaxAge   time.Duration `yaml:"history_max_age"`
+	HistoryMaxBytes int64         `yaml:"history_max_bytes"`
+
+	// Jobs not printed this long after they were due are dropped as expired (0 = never)
+	ExpireAfter time.Duration `yaml:"expire_after,omitempty"`
+
+	// How long job IDs are remembered, so redelivered jobs print at most once
+	DedupeTTL time.Duration `yaml:"dedupe_ttl"`
+
+	// Payloads of finished jobs kept in memory for reprinting, bounded by total size and age
+	ReprintMaxBytes  int           `yaml:"reprint_max_bytes"`
+	ReprintRetention time.Duration `yaml:"reprint_retention"`
+
+	// Default retry policy for failed prints; printers may override it
+	Retry RetryConfig `yaml:"retry"`
+}
+
+// RetryConfig controls how failed prints are retried. Zero fields in a
+// printer's override inherit the default from the jobs section.
+type RetryConfig struct {
+	MaxAttempts    int           `yaml:"max_attempts,omitempty"`    // Total attempts at each copy, including the first
+	InitialBackoff time.Duration `yaml:"initial_backoff,omitempty"` // Doubles after each attempt
+	MaxBackoff     time.Duration `yaml:"max_backoff,omitempty"`
+	RetryOn        []string      `yaml:"retry_on,omitempty"` // "connect" and/or "write"
 }
 
 // PrinterConfig represents a printer configuration
 type PrinterConfig struct {
-	ID         string `yaml:"id"`
-	Name       string `yaml:"name"`
-	Type       string `yaml:"type"` // "usb" or "network"
-	VendorID   string `yaml:"vendor_id,omitempty"`
-	ProductID  string `yaml:"product_id,omitempty"`
-	Address    string `yaml:"address,omitempty"`
-	Port       int    `yaml:"port,omitempty"`
-	PaperWidth int    `yaml:"paper_width,omitempty"` // 58 or 80 (mm)
+	ID         string `yaml:"id" json:"id"`
+	Name       string `yaml:"name" json:"name"`
+	Type       string `yaml:"type" json:"type"` // "usb" or "network"
+	VendorID   string `yaml:"vendor_id,omitempty" json:"vendor_id,omitempty"`
+	ProductID  string `yaml:"product_id,omitempty" json:"product_id,omitempty"`
+	Address    string `yaml:"address,omitempty" json:"address,omitempty"`
+	Port       int    `yaml:"port,omitempty" json:"port,omitempty"`
+	PaperWidth int    `yaml:"paper_width,omitempty" json:"paper_width,omitempty"` // 58 or 80 (mm)
+	Paused     bool   `yaml:"paused,omitempty" json:"paused,omitempty"`           // Jobs queue locally until resumed
+
+	// Command set and character encoding used for text printed by this server
+	Profile  string `yaml:"profile,omitempty" json:"profile,omitempty"`     // generic or epson
+	CodePage string `yaml:"code_page,omitempty" json:"code_page,omitempty"` // cp437 (default), cp850, cp858, cp866 or cp1252
+
+	// Follow the printer across address changes: "address" (default), "mac", "serial" or "snmp_serial".
+	// The matching identifier is learned automatically if left empty.
+	PinBy      string `yaml:"pin_by,omitempty" json:"pin_by,omitempty"`
+	MAC        string `yaml:"mac,omitempty" json:"mac,omitempty"`
+	Serial     string `yaml:"serial,omitempty" json:"serial,omitempty"`
+	SNMPSerial string `yaml:"snmp_serial,omitempty" json:"snmp_serial,omitempty"`
+
+	// Overrides the default retry policy in the jobs section
+	Retry *RetryConfig `yaml:"retry,omitempty" json:"-"`
+
+	// Network printers receive data in chunks, each with its own write deadline, optionally
+	// throttled for slow printers. Zero uses the defaults (4096 bytes, 10s, unthrottled).
+	ChunkSize      int           `yaml:"chunk_size,omitempty" json:"chunk_size,omitempty"`
+	ChunkTimeout   time.Duration `yaml:"chunk_timeout,omitempty" json:"-"`
+	MaxBytesPerSec int           `yaml:"max_bytes_per_sec,omitempty" json:"max_bytes_per_sec,omitempty"`
 }
 
 // Default returns the default configuration
 func Default() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port: 8080,
-			Host: "0.0.0.0",
+			Port:            8080,
+			Host:            "0.0.0.0",
+			ResolveInterval: 1 * time.Minute,
+			HealthInterval:  15 * time.Second,
 		},
 		Cloud: CloudConfig{
-			Endpoint:          "https://api.jetsetgo.world/api/v1/print",
-			WSEndpoint:        "wss://api.jetsetgo.world/api/v1/print/servers/{server_id}/ws",
-			UseWebSocket:      false, // Default to polling; WS requires reverse proxy config
-			WSReconnectDelay:  1 * time.Second,
-			WSMaxReconnect:    30 * time.Second,
-			WSPingInterval:    30 * time.Second,
-			PollInterval:      5 * time.Second,
+			Endpoint:         "https://api.jetsetgo.world/api/v1/print",
+			WSEndpoint:       "wss://api.jetsetgo.world/api/v1/print/servers/{server_id}/ws",
+			UseWebSocket:     false, // Default to polling; WS requires reverse proxy config
+			WSReconnectDelay: 1 * time.Second,
+			WSMaxReconnect:   30 * time.Second,
+			WSPingInterval:   30 * time.Second,
+			WSFallbackAfter:  3,
+			PollInterval:     5 * time.Second,
+		},
+		Jobs: JobsConfig{
+			DataDir:          "data",
+			QueueRetention:   24 * time.Hour,
+			ReplayInterval:   10 * time.Second,
+			DedupeTTL:        24 * time.Hour,
+			ReprintMaxBytes:  16 << 20,
+			ReprintRetention: 24 * time.Hour,
+			HistorySize:      10000,
+			HistoryMaxAge:    30 * 24 * time.Hour,
+			HistoryMaxBytes:  64 << 20,
+			Retry: RetryConfig{
+				MaxAttempts:    5,
+				InitialBackoff: 2 * time.Second,
+				MaxBackoff:     30 * time.Second,
+				RetryOn:        []string{"connect", "write"},
+			},
 		},
 		Printers: []PrinterConfig{},
 	}
@@ -0,0 +1,159 @@
+package escpos
+
+import "strings"
+
+// Symbology is a 1D barcode type, by its GS k function B number
+type Symbology byte
+
+const (
+	UPCA    Symbology = 65
+	EAN13   Symbology = 67
+	EAN8    Symbology = 68
+	Code39  Symbology = 69
+	ITF     Symbology = 70
+	Code128 Symbology = 73
+)
+
+// symbologies name each symbology and check its data
+var symbologies = map[Symbology]struct {
+	name  string
+	valid func(string) bool
+}{
+	UPCA:    {"UPCA", func(s string) bool { return isDigits(s) && (len(s) == 11 || len(s) == 12) }},
+	EAN13:   {"EAN13", func(s string) bool { return isDigits(s) && (len(s) == 12 || len(s) == 13) }},
+	EAN8:    {"EAN8", func(s string) bool { return isDigits(s) && (len(s) == 7 || len(s) == 8) }},
+	Code39:  {"CODE39", func(s string) bool { return strings.Trim(s, "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ -.$/+%") == "" }},
+	ITF:     {"ITF", func(s string) bool { return isDigits(s) && len(s)%2 == 0 }},
+	Code128: {"CODE128", isASCII},
+}
+
+// ParseSymbology looks up a symbology by name, such as "CODE128", ignoring case
+func ParseSymbology(name string) (Symbology, bool) {
+	for sym, s := range symbologies {
+		if strings.EqualFold(s.name, name) {
+			return sym, true
+		}
+	}
+	return 0, false
+}
+
+func (s Symbology) String() string {
+	if sym, ok := symbologies[s]; ok {
+		return sym.name
+	}
+	return "unknown"
+}
+
+// Barcode settings
+const (
+	barcodeHeight = 80 // Dots
+	barcodeModule = 2  // Narrowest bar width in dots
+)
+
+// maxQRData is the most bytes a QR code can hold (version 40, level L)
+const maxQRData = 2953
+
+// maxPDF417Data is the most bytes a PDF417 symbol can hold in byte compaction
+const maxPDF417Data = 1108
+
+// 2D symbol types for GS ( k
+const (
+	symbolPDF417 = 48
+	symbolQR     = 49
+)
+
+// Barcode prints a 1D barcode on its own line with its text below (GS k). The data must
+// suit the symbology: digits of the right length for EAN and UPC, an even number of
+// digits for ITF, and printable ASCII for CODE128.
+func (b *Builder) Barcode(sym Symbology, data string) *Builder {
+	s, ok := symbologies[sym]
+	if !ok {
+		return b.fail("unknown barcode symbology %d", sym)
+	}
+	if data == "" || !s.valid(data) {
+		return b.fail("invalid %s barcode data %q", s.name, data)
+	}
+	if sym == Code128 {
+		// Code set B. { starts a function code, so a literal one is written {{.
+		data = "{B" + strings.ReplaceAll(data, "{", "{{")
+	}
+	if len(data) > 255 {
+		return b.fail("%s barcode data is too long", s.name)
+	}
+	b.cmd(
+		gs, 'H', 2, // Text below
+		gs, 'h', barcodeHeight,
+		gs, 'w', barcodeModule,
+		gs, 'k', byte(sym), byte(len(data)))
+	return b.Raw([]byte(data)).Newline()
+}
+
+// QRCode prints a QR code on its own line, model 2 with error correction M. Printers
+// whose profile lacks QR support print the data as text instead.
+func (b *Builder) QRCode(data string) *Builder {
+	if data == "" {
+		return b.fail("qr needs data")
+	}
+	if len(data) > maxQRData {
+		return b.fail("qr data is %d bytes; the most is %d", len(data), maxQRData)
+	}
+	if !b.prof().QRCode {
+		return b.Line(data)
+	}
+	b.symbolFunc(symbolQR, 65, 50, 0) // Model 2
+	b.symbolFunc(symbolQR, 67, 6)     // Module size
+	b.symbolFunc(symbolQR, 69, 49)    // Error correction M
+	return b.storeAndPrint(symbolQR, data)
+}
+
+// PDF417 prints a PDF417 symbol on its own line with automatic sizing. Printers whose
+// profile lacks PDF417 support print the data as text instead.
+func (b *Builder) PDF417(data string) *Builder {
+	if data == "" {
+		return b.fail("pdf417 needs data")
+	}
+	if len(data) > maxPDF417Data {
+		return b.fail("pdf417 data is %d bytes; the most is %d", len(data), maxPDF417Data)
+	}
+	if !b.prof().PDF417 {
+		return b.Line(data)
+	}
+	b.symbolFunc(symbolPDF417, 67, 3)      // Module width
+	b.symbolFunc(symbolPDF417, 68, 3)      // Row height
+	b.symbolFunc(symbolPDF417, 69, 48, 49) // Error correction level 1
+	return b.storeAndPrint(symbolPDF417, data)
+}
+
+// storeAndPrint stores 2D symbol data (function 80) and prints it (function 81)
+func (b *Builder) storeAndPrint(symbol byte, data string) *Builder {
+	store := len(data) + 3
+	b.cmd(gs, '(', 'k', byte(store), byte(store>>8), symbol, 80, 48)
+	b.Raw([]byte(data))
+	b.symbolFunc(symbol, 81, 48)
+	return b.Newline()
+}
+
+// symbolFunc sends a GS ( k function for a 2D symbol type
+func (b *Builder) symbolFunc(symbol, fn byte, args ...byte) *Builder {
+	n := len(args) + 2
+	b.cmd(gs, '(', 'k', byte(n), byte(n>>8), symbol, fn)
+	return b.Raw(args)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7E {
+			return false
+		}
+	}
+	return true
+}
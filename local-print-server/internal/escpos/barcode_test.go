@@ -0,0 +1,58 @@
+package escpos
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBarcodeCode128EscapesBrace(t *testing.T) {
+	var b Builder
+	b.Barcode(Code128, "a{b}")
+	if err := b.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte{gs, 'k', byte(Code128), 7}, "{Ba{{b}\n"...)
+	if !bytes.HasSuffix(b.Bytes(), want) {
+		t.Errorf("barcode = %q, want it to end %q", b.Bytes(), want)
+	}
+}
+
+func TestBarcodeLength(t *testing.T) {
+	var b Builder
+	b.Barcode(Code128, strings.Repeat("x", 253))
+	if err := b.Err(); err != nil {
+		t.Errorf("253 characters: %v", err)
+	}
+
+	// Escaping counts towards the 255-byte limit
+	b = Builder{}
+	b.Barcode(Code128, strings.Repeat("{", 127))
+	if b.Err() == nil {
+		t.Error("127 braces (256 bytes escaped) were accepted")
+	}
+}
+
+func TestBarcodeValidation(t *testing.T) {
+	tests := []struct {
+		sym  Symbology
+		data string
+		ok   bool
+	}{
+		{EAN13, "400638133393", true},
+		{EAN13, "40063813339", false},
+		{EAN13, "40063813339x", false},
+		{ITF, "1234", true},
+		{ITF, "123", false},
+		{Code39, "ABC-123", true},
+		{Code39, "abc", false},
+		{Code128, "tab\there", false},
+	}
+	for _, tt := range tests {
+		var b Builder
+		b.Barcode(tt.sym, tt.data)
+		if (b.Err() == nil) != tt.ok {
+			t.Errorf("%s %q: err = %v, want ok = %v", tt.sym, tt.data, b.Err(), tt.ok)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package escpos
+
+import "sync"
+
+// codePages holds the characters of bytes 0x80-0xFF for each supported code page
+var codePages = map[string]string{
+	"cp437": "ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜ¢£¥₧ƒáíóúñÑªº¿⌐¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐" +
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0",
+	"cp850": "ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜø£Ø×ƒáíóúñÑªº¿®¬½¼¡«»░▒▓│┤ÁÂÀ©╣║╗╝¢¥┐" +
+		"└┴┬├─┼ãÃ╚╔╩╦╠═╬¤ðÐÊËÈıÍÎÏ┘┌█▄¦Ì▀ÓßÔÒõÕµþÞÚÛÙýÝ¯´\u00ad±‗¾¶§÷¸°¨·¹³²■\u00a0",
+	// cp850 with the euro sign in place of dotless i
+	"cp858": "ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜø£Ø×ƒáíóúñÑªº¿®¬½¼¡«»░▒▓│┤ÁÂÀ©╣║╗╝¢¥┐" +
+		"└┴┬├─┼ãÃ╚╔╩╦╠═╬¤ðÐÊËÈ€ÍÎÏ┘┌█▄¦Ì▀ÓßÔÒõÕµþÞÚÛÙýÝ¯´\u00ad±‗¾¶§÷¸°¨·¹³²■\u00a0",
+	"cp866": "АБВГДЕЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯабвгдежзийклмноп░▒▓│┤╡╢╖╕╣║╗╝╜╛┐" +
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀рстуфхцчшщъыьэюяЁёЄєЇїЎў°∙·√№¤■\u00a0",
+	// Unassigned positions hold U+FFFD, which never matches
+	"cp1252": "€�‚ƒ„…†‡ˆ‰Š‹Œ�Ž��‘’“”•–—˜™š›œ�žŸ" +
+		"\u00a0¡¢£¤¥¦§¨©ª«¬\u00ad®¯°±²³´µ¶·¸¹º»¼½¾¿ÀÁÂÃÄÅÆÇÈÉÊËÌÍÎÏÐÑÒÓÔÕÖ×ØÙÚÛÜÝÞß" +
+		"àáâãäåæçèéêëìíîïðñòóôõö÷øùúûüýþÿ",
+}
+
+// decoders holds the code pages as rune tables, for decoding and building encoders
+var decoders = func() map[string][]rune {
+	m := make(map[string][]rune, len(codePages))
+	for name, chars := range codePages {
+		m[name] = []rune(chars)
+	}
+	return m
+}()
+
+var (
+	encodersMu sync.Mutex
+	encoders   = make(map[string]map[rune]byte)
+)
+
+// encoder returns the rune-to-byte table for the upper half of a code page
+func encoder(codePage string) (map[rune]byte, bool) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	if enc, ok := encoders[codePage]; ok {
+		return enc, true
+	}
+	chars, ok := decoders[codePage]
+	if !ok {
+		return nil, false
+	}
+	enc := make(map[rune]byte, 128)
+	for i, r := range chars {
+		if r != '�' {
+			enc[r] = byte(0x80 + i)
+		}
+	}
+	encoders[codePage] = enc
+	return enc, true
+}
+
+// ValidCodePage reports whether a code page name is supported; "" is the default
+func ValidCodePage(name string) bool {
+	_, ok := codePages[name]
+	return ok || name == ""
+}
+
+// Encode converts UTF-8 text to a code page. Characters the code page lacks become '?'.
+func Encode(text, codePage string) []byte {
+	if codePage == "" {
+		codePage = DefaultCodePage
+	}
+	enc, _ := encoder(codePage)
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch b, ok := enc[r]; {
+		case r < 0x80:
+			out = append(out, byte(r))
+		case ok:
+			out = append(out, b)
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// Decode returns the character a byte stands for in a code page. Unknown code pages
+// decode as DefaultCodePage.
+func Decode(b byte, codePage string) rune {
+	if b < 0x80 {
+		return rune(b)
+	}
+	chars, ok := decoders[codePage]
+	if !ok {
+		chars = decoders[DefaultCodePage]
+	}
+	return chars[b-0x80]
+}
+
+// CodePageName returns the name of the code page a profile selects with ESC t n
+func (p Profile) CodePageName(n byte) (string, bool) {
+	for name, num := range p.CodePages {
+		if num == n {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,196 @@
+// Package escpos builds ESC/POS command streams. A Builder knows the printer's profile
+// and the code page it has selected, so text is encoded correctly and features the
+// printer lacks degrade to something it can print.
+//
+//	b := escpos.NewBuilder(profile)
+//	b.Init().Align(escpos.AlignCenter).Bold(true).Line("RECEIPT").Bold(false)
+//	b.Barcode(escpos.Code128, "A-1234").Cut()
+//	data, err := b.Bytes(), b.Err()
+package escpos
+
+import "fmt"
+
+// Control bytes
+const (
+	lf  = 0x0A
+	esc = 0x1B
+	gs  = 0x1D
+)
+
+// Align is a justification for ESC a
+type Align byte
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// Builder appends ESC/POS commands to a buffer. The first error, such as barcode data
+// the symbology can't encode, is kept in Err and later calls do nothing, so a sequence
+// of calls can be checked once at the end. The zero Builder writes for the default
+// profile.
+type Builder struct {
+	profile  Profile
+	codePage string // Selected code page; "" until ESC t is sent, which is DefaultCodePage
+	buf      []byte
+	err      error
+}
+
+// NewBuilder returns a Builder for a printer profile
+func NewBuilder(profile Profile) *Builder {
+	return &Builder{profile: profile}
+}
+
+// Bytes returns the commands built so far
+func (b *Builder) Bytes() []byte {
+	return b.buf
+}
+
+// Len returns the number of bytes built so far
+func (b *Builder) Len() int {
+	return len(b.buf)
+}
+
+// Err returns the first error, if any
+func (b *Builder) Err() error {
+	return b.err
+}
+
+// ok reports whether commands may still be added
+func (b *Builder) ok() bool {
+	return b.err == nil
+}
+
+func (b *Builder) fail(format string, args ...interface{}) *Builder {
+	if b.err == nil {
+		b.err = fmt.Errorf(format, args...)
+	}
+	return b
+}
+
+// prof returns the profile, the default one for a zero Builder
+func (b *Builder) prof() Profile {
+	if b.profile.Name == "" {
+		p, _ := LookupProfile(DefaultProfile)
+		return p
+	}
+	return b.profile
+}
+
+// Raw appends bytes as they are
+func (b *Builder) Raw(data []byte) *Builder {
+	if b.ok() {
+		b.buf = append(b.buf, data...)
+	}
+	return b
+}
+
+func (b *Builder) cmd(data ...byte) *Builder {
+	return b.Raw(data)
+}
+
+// Init resets the printer to its power-on settings (ESC @)
+func (b *Builder) Init() *Builder {
+	if b.ok() {
+		b.codePage = ""
+	}
+	return b.cmd(esc, '@')
+}
+
+// CodePage selects the code page text is encoded in (ESC t), by the profile's numbering.
+// "" is DefaultCodePage.
+func (b *Builder) CodePage(name string) *Builder {
+	if name == "" {
+		name = DefaultCodePage
+	}
+	prof := b.prof()
+	n, ok := prof.CodePages[name]
+	if !ok || !ValidCodePage(name) {
+		return b.fail("code page %s is not supported by the %s profile", name, prof.Name)
+	}
+	if b.ok() {
+		b.codePage = name
+	}
+	return b.cmd(esc, 't', n)
+}
+
+// Text appends text encoded in the selected code page. Characters it lacks print as '?'.
+func (b *Builder) Text(s string) *Builder {
+	return b.Raw(Encode(s, b.codePage))
+}
+
+// Line appends text and a line feed
+func (b *Builder) Line(s string) *Builder {
+	return b.Text(s).Newline()
+}
+
+// Newline prints the current line (LF)
+func (b *Builder) Newline() *Builder {
+	return b.cmd(lf)
+}
+
+// Bold turns emphasis on or off (ESC E)
+func (b *Builder) Bold(on bool) *Builder {
+	return b.cmd(esc, 'E', boolByte(on))
+}
+
+// Underline turns single underline on or off (ESC -)
+func (b *Builder) Underline(on bool) *Builder {
+	return b.cmd(esc, '-', boolByte(on))
+}
+
+// Invert turns white-on-black printing on or off (GS B)
+func (b *Builder) Invert(on bool) *Builder {
+	return b.cmd(gs, 'B', boolByte(on))
+}
+
+// Size sets the character magnification (GS !). Width and height are 1 to 8.
+func (b *Builder) Size(width, height int) *Builder {
+	width = min(max(width, 1), 8)
+	height = min(max(height, 1), 8)
+	return b.cmd(gs, '!', byte(width-1)<<4|byte(height-1))
+}
+
+// Align sets the justification of lines that start after it (ESC a)
+func (b *Builder) Align(a Align) *Builder {
+	return b.cmd(esc, 'a', byte(a))
+}
+
+// Feed prints the current line and feeds lines, up to 255 (ESC d)
+func (b *Builder) Feed(lines int) *Builder {
+	if lines <= 0 {
+		return b
+	}
+	return b.cmd(esc, 'd', byte(min(lines, 255)))
+}
+
+// Cut feeds to the cutter and makes a partial cut (GS V 66 0)
+func (b *Builder) Cut() *Builder {
+	return b.cmd(gs, 'V', 66, 0)
+}
+
+// FullCut feeds to the cutter and cuts through (GS V 65 0)
+func (b *Builder) FullCut() *Builder {
+	return b.cmd(gs, 'V', 65, 0)
+}
+
+// OpenDrawer pulses cash drawer pin 2 for 50ms on, 500ms off (ESC p 0)
+func (b *Builder) OpenDrawer() *Builder {
+	return b.cmd(esc, 'p', 0, 25, 250)
+}
+
+// Beep sounds the buzzer n times, 1 to 9, for 100ms each (ESC B)
+func (b *Builder) Beep(n int) *Builder {
+	if n <= 0 {
+		return b
+	}
+	return b.cmd(esc, 'B', byte(min(n, 9)), 2)
+}
+
+func boolByte(on bool) byte {
+	if on {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package escpos
+
+import "image"
+
+// maxBandRows is the height of each GS v 0 band; some printers reject taller images
+const maxBandRows = 255
+
+// Largest image Image prints, in dots. They bound the memory used for dithering.
+const (
+	maxImageDots = 1024 // Wider than any receipt printer
+	maxImageRows = 4096
+)
+
+// Image prints an image as GS v 0 raster bands, scaled down to at most maxDots wide
+// (maxImageDots if 0) and dithered to black and white. Transparent pixels print as
+// paper. Images taller than maxImageRows once scaled are an error.
+func (b *Builder) Image(img image.Image, maxDots int) *Builder {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return b.fail("empty image")
+	}
+	if maxDots <= 0 || maxDots > maxImageDots {
+		maxDots = maxImageDots
+	}
+	if w > maxDots {
+		h = h * maxDots / w
+		w = maxDots
+	}
+	h = max(h, 1)
+	if h > maxImageRows {
+		return b.fail("image is %d dots tall once scaled; the most is %d", h, maxImageRows)
+	}
+
+	// Luminance on white, scaled by nearest neighbour
+	gray := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sx := bounds.Min.X + x*bounds.Dx()/w
+			sy := bounds.Min.Y + y*bounds.Dy()/h
+			cr, cg, cb, ca := img.At(sx, sy).RGBA()
+			// RGBA is alpha-premultiplied, so adding the transparency composites over white
+			lum := (0.299*float64(cr) + 0.587*float64(cg) + 0.114*float64(cb)) / 0xFFFF
+			gray[y*w+x] = lum + 1 - float64(ca)/0xFFFF
+		}
+	}
+
+	// Floyd-Steinberg dithering
+	widthBytes := (w + 7) / 8
+	bits := make([]byte, widthBytes*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			old := gray[y*w+x]
+			black := old < 0.5
+			quant := 1.0
+			if black {
+				quant = 0
+				bits[y*widthBytes+x/8] |= 0x80 >> (x % 8)
+			}
+			diff := old - quant
+			if x+1 < w {
+				gray[y*w+x+1] += diff * 7 / 16
+			}
+			if y+1 < h {
+				if x > 0 {
+					gray[(y+1)*w+x-1] += diff * 3 / 16
+				}
+				gray[(y+1)*w+x] += diff * 5 / 16
+				if x+1 < w {
+					gray[(y+1)*w+x+1] += diff * 1 / 16
+				}
+			}
+		}
+	}
+	return b.Raster(bits, widthBytes, h)
+}
+
+// Raster prints a 1-bit image (GS v 0): rows of widthBytes bytes, most significant bit
+// leftmost, set bits black. Tall images are sent in bands.
+func (b *Builder) Raster(bits []byte, widthBytes, height int) *Builder {
+	if widthBytes <= 0 || height <= 0 || len(bits) < widthBytes*height {
+		return b.fail("invalid raster image")
+	}
+	for y := 0; y < height; y += maxBandRows {
+		rows := min(maxBandRows, height-y)
+		b.cmd(gs, 'v', '0', 0, // Normal scale
+			byte(widthBytes), byte(widthBytes>>8), byte(rows), byte(rows>>8))
+		b.Raw(bits[y*widthBytes : (y+rows)*widthBytes])
+	}
+	return b
+}
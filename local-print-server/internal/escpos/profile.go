@@ -0,0 +1,52 @@
+package escpos
+
+import "sort"
+
+// Profile describes what a family of printers supports
+type Profile struct {
+	Name      string
+	CodePages map[string]byte // Code page name to its ESC t number
+	QRCode    bool            // GS ( k QR codes; without it the QR data is printed as text
+	PDF417    bool            // GS ( k PDF417 symbols; without it the data is printed as text
+}
+
+// epsonCodePages are the ESC t numbers of the Epson command set. Star, Bixolon
+// and Citizen printers in ESC/POS mode use the same numbers, so they share the
+// epson profile rather than having profiles of their own.
+var epsonCodePages = map[string]byte{
+	"cp437":  0,
+	"cp850":  2,
+	"cp858":  19,
+	"cp866":  17,
+	"cp1252": 16,
+}
+
+var profiles = map[string]Profile{
+	"generic": {Name: "generic", CodePages: epsonCodePages},
+	"epson":   {Name: "epson", CodePages: epsonCodePages, QRCode: true, PDF417: true},
+}
+
+// DefaultProfile is used for printers without a profile
+const DefaultProfile = "generic"
+
+// DefaultCodePage is the printers' power-on code page
+const DefaultCodePage = "cp437"
+
+// LookupProfile returns a profile by name; "" is the default profile
+func LookupProfile(name string) (Profile, bool) {
+	if name == "" {
+		name = DefaultProfile
+	}
+	p, ok := profiles[name]
+	return p, ok
+}
+
+// ProfileNames returns the known profile names, sorted
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
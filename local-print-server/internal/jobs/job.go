@@ -0,0 +1,44 @@
+package jobs
+
+import "time"
+
+// Job sources
+const (
+	SourceWebSocket = "websocket"
+	SourcePolling   = "polling"
+	SourceLocal     = "local"
+)
+
+// Job is a print job held in the local queue until it has been printed
+type Job struct {
+	ID        string    `json:"id"`
+	PrinterID string    `json:"printer_id"`
+	Data      []byte    `json:"data"`
+	Source    string    `json:"source"`             // websocket, polling or local
+	Priority  int       `json:"priority,omitempty"` // Higher prints first; FIFO within a priority
+	CreatedAt time.Time `json:"created_at"`
+	NotBefore time.Time `json:"not_before"` // Scheduled jobs are held until this time
+	LastError string    `json:"last_error,omitempty"`
+
+	Options       Options `json:"options,omitempty"`
+	CopiesPrinted int     `json:"copies_printed,omitempty"`
+
+	Timing Timing `json:"timing"`
+}
+
+// Timing records when a job reached each stage, to show where cloud-to-paper time goes.
+// Dequeued, Connected and Written describe the latest print attempt.
+type Timing struct {
+	ReceivedAt  *time.Time `json:"received_at,omitempty"`  // Accepted by this server
+	DequeuedAt  *time.Time `json:"dequeued_at,omitempty"`  // Taken from the printer's queue
+	ConnectedAt *time.Time `json:"connected_at,omitempty"` // Printer connection open
+	WrittenAt   *time.Time `json:"written_at,omitempty"`   // Last copy written to the printer
+}
+
+// readyAt returns when the job became ready to print
+func (j Job) readyAt() time.Time {
+	if j.NotBefore.After(j.CreatedAt) {
+		return j.NotBefore
+	}
+	return j.CreatedAt
+}
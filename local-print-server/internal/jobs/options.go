@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"strconv"
+
+	"github.com/jetsetgo/local-print-server/internal/escpos"
+)
+
+// maxCopies bounds the copies a single job may request
+const maxCopies = 20
+
+// Options are the job options applied locally on top of the ESC/POS data
+type Options struct {
+	Copies     int  `json:"copies,omitempty"`      // Copies to print (default 1)
+	Cut        bool `json:"cut,omitempty"`         // Partial cut after each copy
+	Feed       int  `json:"feed,omitempty"`        // Lines to feed after each copy, before the cut
+	Beep       int  `json:"beep,omitempty"`        // Buzzer beeps once the last copy is printed
+	OpenDrawer bool `json:"open_drawer,omitempty"` // Kick the cash drawer once the last copy is printed
+}
+
+// ParseOptions reads options from a cloud job. Unknown keys are ignored; values may be
+// numbers, booleans or numeric strings. "beep": true means a single beep.
+func ParseOptions(m map[string]interface{}) Options {
+	var o Options
+	if m == nil {
+		return o
+	}
+	o.Copies = optionInt(m["copies"])
+	o.Cut = optionBool(m["cut"])
+	o.Feed = optionInt(m["feed"])
+	if o.Feed == 0 {
+		o.Feed = optionInt(m["feed_lines"])
+	}
+	o.Beep = optionInt(m["beep"])
+	o.OpenDrawer = optionBool(m["open_drawer"])
+	return o
+}
+
+// CopyCount returns the number of copies to print, within [1, maxCopies]
+func (o Options) CopyCount() int {
+	switch {
+	case o.Copies < 1:
+		return 1
+	case o.Copies > maxCopies:
+		return maxCopies
+	}
+	return o.Copies
+}
+
+// CopyData returns the bytes to send for copy n (0-based) of a job
+func (o Options) CopyData(data []byte, n int) []byte {
+	out := make([]byte, 0, o.copySize(len(data), n))
+	out = append(out, data...)
+	return append(out, o.trailer(n)...)
+}
+
+// trailer returns the commands the options add after copy n
+func (o Options) trailer(n int) []byte {
+	var b escpos.Builder
+	b.Feed(o.Feed)
+	if o.Cut {
+		b.Cut()
+	}
+	if n == o.CopyCount()-1 {
+		if o.OpenDrawer {
+			b.OpenDrawer()
+		}
+		b.Beep(o.Beep)
+	}
+	return b.Bytes()
+}
+
+// copySize returns len(o.CopyData(data, n)) for data of length dataLen
+func (o Options) copySize(dataLen, n int) int {
+	return dataLen + len(o.trailer(n))
+}
+
+func optionInt(v interface{}) int {
+	switch x := v.(type) {
+	case float64:
+		return int(x)
+	case int:
+		return x
+	case bool:
+		if x {
+			return 1
+		}
+	case string:
+		n, _ := strconv.Atoi(x)
+		return n
+	}
+	return 0
+}
+
+func optionBool(v interface{}) bool {
+	switch x := v.(type) {
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		b, _ := strconv.ParseBool(x)
+		return b
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package jobs
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Queue is a persistent on-disk job queue. Each job is stored as its own
+// JSON file, so a crash mid-write can only ever affect a single job.
+type Queue struct {
+	dir  string
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// OpenQueue opens (or creates) the queue in dir and loads any jobs left from a previous run
+func OpenQueue(dir string) (*Queue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	q := &Queue{
+		dir:  dir,
+		jobs: make(map[string]*Job),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue directory: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil || job.ID == "" {
+			continue
+		}
+		q.jobs[job.ID] = &job
+	}
+
+	return q, nil
+}
+
+// Put writes a job to disk. The caller must Remove it once it has been printed.
+func (q *Queue) Put(job Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err := q.write(&job); err != nil {
+		return err
+	}
+	q.jobs[job.ID] = &job
+	return nil
+}
+
+// Update rewrites a queued job, e.g. to record why it could not be printed.
+// Does nothing if the job is no longer queued.
+func (q *Queue) Update(job Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.jobs[job.ID]; !ok {
+		return nil
+	}
+	if err := q.write(&job); err != nil {
+		return err
+	}
+	q.jobs[job.ID] = &job
+	return nil
+}
+
+// Remove deletes a job from the queue
+func (q *Queue) Remove(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.jobs[id]; !ok {
+		return nil
+	}
+	delete(q.jobs, id)
+
+	if err := os.Remove(q.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Get returns a queued job by ID
+func (q *Queue) Get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Pending returns the queued jobs, oldest first
+func (q *Queue) Pending() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	result := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		result = append(result, *job)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// Len returns the number of jobs in the queue
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+// write atomically stores a job on disk (temp file + rename)
+func (q *Queue) write(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	tmp := q.path(job.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write queued job: %w", err)
+	}
+	if err := os.Rename(tmp, q.path(job.ID)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write queued job: %w", err)
+	}
+	return nil
+}
+
+// path returns the file for a job. IDs come from the cloud, so they are hex-encoded
+// to keep them filesystem-safe.
+func (q *Queue) path(id string) string {
+	return filepath.Join(q.dir, hex.EncodeToString([]byte(id))+".json")
+}
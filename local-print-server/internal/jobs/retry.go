@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jetsetgo/local-print-server/internal/printer"
+)
+
+// Retryable error classes
+const (
+	RetryOnConnect = "connect" // Printer refused or did not answer the connection
+	RetryOnWrite   = "write"   // Connection dropped while sending data
+)
+
+// RetryPolicy controls how a printer's failed jobs are retried.
+// Errors outside RetryOn (unknown printer, invalid data) always fail immediately.
+type RetryPolicy struct {
+	MaxAttempts    int // Total attempts at each copy, including the first
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	RetryOn        []string
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 2 * time.Second,
+		MaxBackoff:     30 * time.Second,
+		RetryOn:        []string{RetryOnConnect, RetryOnWrite},
+	}
+}
+
+// Retryable reports whether err belongs to a class the policy retries
+func (p RetryPolicy) Retryable(err error) bool {
+	class := errorClass(err)
+	if class == "" {
+		return false
+	}
+	for _, c := range p.RetryOn {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the delay before the given retry (1 for the first retry)
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	d := p.InitialBackoff
+	if d <= 0 {
+		d = time.Second
+	}
+	for i := 1; i < retry; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return d
+}
+
+// errorClass maps a print error to its retry class, or "" if it is not retryable
+func errorClass(err error) string {
+	switch {
+	case errors.Is(err, printer.ErrConnect):
+		return RetryOnConnect
+	case errors.Is(err, printer.ErrWrite):
+		return RetryOnWrite
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ParseSchedule reads a job's scheduled time from its print_at or not_before field
+// (RFC 3339). Returns the zero time if neither is set.
+func ParseSchedule(printAt, notBefore string) (time.Time, error) {
+	value, field := printAt, "print_at"
+	if value == "" {
+		value, field = notBefore, "not_before"
+	}
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %w", field, err)
+	}
+	return t, nil
+}
+
+// schedHeap orders scheduled jobs by release time, then arrival order
+type schedHeap []*item
+
+func (h schedHeap) Len() int { return len(h) }
+func (h schedHeap) Less(i, j int) bool {
+	if !h[i].job.NotBefore.Equal(h[j].job.NotBefore) {
+		return h[i].job.NotBefore.Before(h[j].job.NotBefore)
+	}
+	return h[i].seq < h[j].seq
+}
+func (h schedHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *schedHeap) Push(x any)   { *h = append(*h, x.(*item)) }
+func (h *schedHeap) Pop() any {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	*h = old[:n-1]
+	return it
+}
+
+// scheduler holds jobs until their NotBefore time. The jobs themselves are
+// persisted in the queue, so the schedule survives restarts.
+type scheduler struct {
+	mu    sync.Mutex
+	queue schedHeap
+	wake  chan struct{} // The earliest release time may have changed
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{wake: make(chan struct{}, 1)}
+}
+
+// add schedules an item
+func (sc *scheduler) add(it *item) {
+	sc.mu.Lock()
+	heap.Push(&sc.queue, it)
+	sc.mu.Unlock()
+	signal(sc.wake)
+}
+
+// due removes and returns the items whose time has come, and the time until the next one
+// (0 if none are left)
+func (sc *scheduler) due(now time.Time) (ready []*item, next time.Duration) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	for len(sc.queue) > 0 && !sc.queue[0].job.NotBefore.After(now) {
+		ready = append(ready, heap.Pop(&sc.queue).(*item))
+	}
+	if len(sc.queue) > 0 {
+		next = sc.queue[0].job.NotBefore.Sub(now)
+	}
+	return ready, next
+}
+
+// remove takes a scheduled job out of the schedule
+func (sc *scheduler) remove(jobID string) *item {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	for i, it := range sc.queue {
+		if it.job.ID == jobID {
+			heap.Remove(&sc.queue, i)
+			return it
+		}
+	}
+	return nil
+}
+
+// items returns a snapshot of the scheduled items
+func (sc *scheduler) items() []*item {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return append([]*item(nil), sc.queue...)
+}
@@ -0,0 +1,237 @@
+package jobs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SeenEntry is the last known outcome of a job ID
+type SeenEntry struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// seenCompactAfter is how many log lines beyond the live entries are allowed before
+// the log is rewritten
+const seenCompactAfter = 1000
+
+// seenRecord is a line of the index log
+type seenRecord struct {
+	ID string `json:"id"`
+	SeenEntry
+}
+
+// SeenIndex remembers job IDs that were already accepted, so a job redelivered by the
+// cloud (repeated poll results, WebSocket redelivery after reconnect) prints at most once.
+// Entries expire ttl after their last update.
+//
+// On disk the index is an append-only JSON-lines log of accepted IDs and final statuses.
+// Statuses in between are kept in memory only, so dispatching a job writes nothing;
+// after a restart the job queue answers for jobs that had not finished. The log is
+// rewritten with just the live entries when opened, closed, or grown seenCompactAfter
+// lines past them.
+type SeenIndex struct {
+	path string
+	ttl  time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]*SeenEntry
+	log      *os.File
+	logLines int
+}
+
+// OpenSeenIndex loads the index from path, dropping expired entries
+func OpenSeenIndex(path string, ttl time.Duration) (*SeenIndex, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	idx := &SeenIndex{
+		path:    path,
+		ttl:     ttl,
+		entries: make(map[string]*SeenEntry),
+	}
+	if err := idx.load(); err != nil {
+		return nil, err
+	}
+	if err := idx.compact(time.Now()); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// load replays the log; later lines for an ID replace earlier ones
+func (s *SeenIndex) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read seen-job index: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec seenRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil || rec.ID == "" {
+			continue // A line torn by a crash only weakens duplicate suppression
+		}
+		e := rec.SeenEntry
+		s.entries[rec.ID] = &e
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read seen-job index: %w", err)
+	}
+	return nil
+}
+
+// Close compacts the log and closes it. Later changes are kept in memory only.
+func (s *SeenIndex) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log == nil {
+		return
+	}
+	if err := s.compact(time.Now()); err != nil {
+		log.Printf("Failed to compact seen-job index: %v", err)
+	}
+	if s.log != nil {
+		s.log.Close()
+		s.log = nil
+	}
+}
+
+// Accept records a new job ID with the given status. Returns false and the
+// existing entry if the ID was already seen.
+func (s *SeenIndex) Accept(id, status string) (SeenEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if e, ok := s.entries[id]; ok && now.Sub(e.UpdatedAt) < s.ttl {
+		return *e, false
+	}
+	e := &SeenEntry{Status: status, FirstSeen: now, UpdatedAt: now}
+	s.entries[id] = e
+	s.append(id, e, now)
+	return SeenEntry{}, true
+}
+
+// Update records a job's latest status. Unknown IDs are added. Only final statuses
+// are written to disk.
+func (s *SeenIndex) Update(id, status, errMsg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	e, ok := s.entries[id]
+	if !ok {
+		e = &SeenEntry{FirstSeen: now}
+		s.entries[id] = e
+	}
+	e.Status = status
+	e.Error = errMsg
+	e.UpdatedAt = now
+	if IsFinal(status) {
+		s.append(id, e, now)
+	}
+}
+
+// Get returns the entry for a job ID, if it was seen within the TTL
+func (s *SeenIndex) Get(id string) (SeenEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok || time.Since(e.UpdatedAt) >= s.ttl {
+		return SeenEntry{}, false
+	}
+	return *e, true
+}
+
+// IsFinal reports whether a job status is one the job does not leave
+func IsFinal(status string) bool {
+	switch status {
+	case "completed", "failed", "cancelled", "expired":
+		return true
+	}
+	return false
+}
+
+// prune drops expired entries. Caller must hold mu or own the index exclusively.
+func (s *SeenIndex) prune(now time.Time) {
+	for id, e := range s.entries {
+		if now.Sub(e.UpdatedAt) >= s.ttl {
+			delete(s.entries, id)
+		}
+	}
+}
+
+// append writes an entry to the log, compacting it first if it has grown too far
+// past the live entries. Caller must hold mu.
+func (s *SeenIndex) append(id string, e *SeenEntry, now time.Time) {
+	if s.log == nil {
+		return // Closed
+	}
+	if s.logLines > len(s.entries)+seenCompactAfter {
+		if err := s.compact(now); err != nil {
+			log.Printf("Failed to compact seen-job index: %v", err)
+		}
+		return // The rewritten log holds e
+	}
+	line, err := json.Marshal(seenRecord{ID: id, SeenEntry: *e})
+	if err != nil {
+		return
+	}
+	if _, err := s.log.Write(append(line, '\n')); err != nil {
+		log.Printf("Failed to save seen-job index: %v", err)
+		return
+	}
+	s.logLines++
+}
+
+// compact prunes the index, rewrites the log with the live entries (temp file +
+// rename) and reopens it for appending. Caller must hold mu, except while opening.
+func (s *SeenIndex) compact(now time.Time) error {
+	s.prune(now)
+
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to write seen-job index: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for id, e := range s.entries {
+		enc.Encode(seenRecord{ID: id, SeenEntry: *e})
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write seen-job index: %w", err)
+	}
+	f.Close()
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write seen-job index: %w", err)
+	}
+
+	if s.log != nil {
+		s.log.Close()
+	}
+	s.log, err = os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open seen-job index: %w", err)
+	}
+	s.logLines = len(s.entries)
+	return nil
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSeenIndexPersistsAcceptedAndFinal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.jsonl")
+	idx, err := OpenSeenIndex(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx.Accept("job-1", "queued")
+	idx.Accept("job-2", "queued")
+	before := lines(t, path)
+	for _, status := range []string{"dispatched", "sending", "retrying", "held"} {
+		idx.Update("job-1", status, "")
+	}
+	if n := lines(t, path); n != before {
+		t.Errorf("in-between statuses wrote %d lines, want none", n-before)
+	}
+	idx.Update("job-1", "completed", "")
+	if _, ok := idx.Accept("job-1", "queued"); ok {
+		t.Error("job-1 accepted twice")
+	}
+
+	// Reopened without Close, as after a crash
+	idx, err = OpenSeenIndex(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := idx.Get("job-1"); !ok || e.Status != "completed" {
+		t.Errorf("job-1 = %+v, %v; want completed", e, ok)
+	}
+	if e, ok := idx.Get("job-2"); !ok || e.Status != "queued" {
+		t.Errorf("job-2 = %+v, %v; want queued", e, ok)
+	}
+	if n := lines(t, path); n != 2 {
+		t.Errorf("log has %d lines after reopening, want it compacted to 2", n)
+	}
+	idx.Close()
+}
+
+func TestSeenIndexCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.jsonl")
+	idx, err := OpenSeenIndex(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	idx.Accept("job", "queued")
+	for i := 0; i < 3*seenCompactAfter; i++ {
+		idx.Update("job", "failed", "")
+	}
+	if n := lines(t, path); n > seenCompactAfter+2 {
+		t.Errorf("log has %d lines for one job, want it compacted", n)
+	}
+}
+
+func lines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Count(data, []byte("\n"))
+}
@@ -0,0 +1,658 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jetsetgo/local-print-server/internal/printer"
+)
+
+// progressInterval limits how often OnProgress is called for a job
+const progressInterval = 250 * time.Millisecond
+
+// ErrJobNotFound is returned when cancelling a job that is not waiting or printing
+var ErrJobNotFound = errors.New("job not found or already finished")
+
+// Spooler is the shared print path for cloud and local jobs. Every job is written to
+// the queue, then printed by a per-printer worker that keeps FIFO order within a
+// priority level and lets higher priorities jump ahead. Failed prints are retried
+// with backoff per the printer's RetryPolicy; jobs whose printer stays unreachable
+// remain queued and are retried, including after a restart.
+type Spooler struct {
+	queue    *Queue
+	seen     *SeenIndex
+	printers *printer.Manager
+
+	mu            sync.Mutex
+	workers       map[string]*worker
+	sched         *scheduler
+	waiters       map[string]chan jobResult
+	warned        map[string]bool
+	seq           uint64
+	started       bool
+	replay        []Job // Jobs left by a previous run, enqueued by Start
+	retryInterval time.Duration
+	checkInterval time.Duration // How often an idle or paused worker checks its waiting jobs
+	retention     time.Duration
+	expireAfter   time.Duration
+	done          chan struct{}
+
+	// OnStatus is called whenever a job changes status. A job moves through
+	//   scheduled (if print_at is in the future), queued, dispatched, sending, completed
+	// and may pass through retrying (a failed attempt will be retried) or held (printer
+	// unreachable or paused). It ends completed, failed, cancelled or expired.
+	OnStatus func(job Job, status, errMsg string)
+
+	// OnCopyPrinted is called after each copy of a multi-copy job
+	OnCopyPrinted func(job Job)
+
+	// OnProgress reports bytes written to the printer for a job, across all copies.
+	// Calls are at least progressInterval apart, except for the final one.
+	OnProgress func(job Job, sent, total int)
+
+	// Policy returns the retry policy for a printer. DefaultRetryPolicy is used if nil.
+	Policy func(printerID string) RetryPolicy
+
+	// OnOverdue is called once when a job has been queued longer than the retention
+	OnOverdue func(job Job, age time.Duration)
+}
+
+type jobResult struct {
+	status string
+	errMsg string
+}
+
+// NewSpooler creates a spooler. If queue is nil, jobs are held in memory only;
+// if seen is nil, duplicate job IDs are not suppressed.
+func NewSpooler(queue *Queue, seen *SeenIndex, printers *printer.Manager) *Spooler {
+	s := &Spooler{
+		queue:         queue,
+		seen:          seen,
+		printers:      printers,
+		workers:       make(map[string]*worker),
+		sched:         newScheduler(),
+		waiters:       make(map[string]chan jobResult),
+		warned:        make(map[string]bool),
+		retryInterval: 10 * time.Second,
+		checkInterval: time.Minute,
+		done:          make(chan struct{}),
+	}
+	// Taken now, so jobs submitted before Start are not replayed a second time
+	if queue != nil {
+		s.replay = queue.Pending()
+	}
+	return s
+}
+
+// Start loads jobs left from a previous run, including scheduled ones, and starts the printer workers.
+// Unreachable printers are retried every retryInterval; jobs queued longer than
+// retention are reported through OnOverdue, and jobs not printed within expireAfter
+// of being ready are dropped as expired (0 keeps them until printed).
+func (s *Spooler) Start(retryInterval, retention, expireAfter time.Duration) {
+	s.mu.Lock()
+	if retryInterval > 0 {
+		s.retryInterval = retryInterval
+	}
+	s.retention = retention
+	s.expireAfter = expireAfter
+	s.started = true
+	for _, w := range s.workers {
+		go s.run(w)
+	}
+	replay := s.replay
+	s.replay = nil
+	s.mu.Unlock()
+	go s.runScheduler()
+
+	for _, job := range replay {
+		if s.seen != nil {
+			s.seen.Accept(job.ID, "queued")
+		}
+		s.enqueue(job)
+	}
+}
+
+// Stop stops all workers and closes the seen-job index. Jobs not yet printed remain in the queue.
+func (s *Spooler) Stop() {
+	close(s.done)
+	if s.seen != nil {
+		s.seen.Close()
+	}
+}
+
+// Submit queues a job for printing, or schedules it if its NotBefore time is in the future.
+// Status changes are reported through OnStatus. If the job ID was already accepted, the job
+// is dropped and the earlier entry is returned with accepted=false, so the caller can
+// re-acknowledge it.
+func (s *Spooler) Submit(job Job) (prev SeenEntry, accepted bool) {
+	if prev, ok := s.Seen(job.ID); ok {
+		return prev, false
+	}
+	if s.seen != nil {
+		if prev, ok := s.seen.Accept(job.ID, "queued"); !ok {
+			return prev, false
+		}
+	}
+	if job.Timing.ReceivedAt == nil {
+		received := job.CreatedAt
+		if received.IsZero() {
+			received = time.Now()
+		}
+		job.Timing.ReceivedAt = &received
+	}
+	if s.queue != nil {
+		if err := s.queue.Put(job); err != nil {
+			log.Printf("Failed to persist job %s, holding in memory only: %v", job.ID, err)
+		}
+	}
+	if s.enqueue(job) {
+		s.emit(job, "scheduled", "")
+	}
+	return SeenEntry{}, true
+}
+
+// SubmitWait queues a job and waits until it is printed, fails, is scheduled, or is held
+// because the printer is unreachable or paused. Returns "queued" if none of these happen
+// within timeout.
+func (s *Spooler) SubmitWait(job Job, timeout time.Duration) (status, errMsg string) {
+	ch := make(chan jobResult, 1)
+	s.mu.Lock()
+	s.waiters[job.ID] = ch
+	s.mu.Unlock()
+
+	if prev, ok := s.Submit(job); !ok {
+		s.mu.Lock()
+		delete(s.waiters, job.ID)
+		s.mu.Unlock()
+		return prev.Status, prev.Error
+	}
+
+	select {
+	case r := <-ch:
+		return r.status, r.errMsg
+	case <-time.After(timeout):
+		s.mu.Lock()
+		delete(s.waiters, job.ID)
+		s.mu.Unlock()
+		return "queued", ""
+	}
+}
+
+// Cancel cancels a job. A scheduled, waiting or held job is removed at once. A job being printed
+// stops after the chunk being written (inFlight is true), which may leave part of a line or
+// image unprinted.
+func (s *Spooler) Cancel(jobID string) (inFlight bool, err error) {
+	if it := s.sched.remove(jobID); it != nil {
+		s.finish(it.job, "cancelled", "")
+		return false, nil
+	}
+
+	s.mu.Lock()
+	workers := make([]*worker, 0, len(s.workers))
+	for _, w := range s.workers {
+		workers = append(workers, w)
+	}
+	s.mu.Unlock()
+
+	for _, w := range workers {
+		it, inFlight := w.remove(jobID)
+		if it == nil {
+			continue
+		}
+		if !inFlight {
+			s.finish(it.job, "cancelled", "")
+		}
+		return inFlight, nil
+	}
+	return false, ErrJobNotFound
+}
+
+// Reject records a job ID that has not been received yet as cancelled,
+// so it is not printed if it is delivered later
+func (s *Spooler) Reject(jobID string) {
+	if s.seen != nil {
+		s.seen.Update(jobID, "cancelled", "")
+	}
+}
+
+// Seen returns the last known status of a job ID that was already accepted.
+// Jobs still in the queue count as seen even if their index entry has expired,
+// e.g. a job scheduled further ahead than the dedupe TTL.
+func (s *Spooler) Seen(jobID string) (SeenEntry, bool) {
+	if s.seen != nil {
+		if e, ok := s.seen.Get(jobID); ok {
+			return e, true
+		}
+	}
+	if s.queue != nil {
+		if job, ok := s.queue.Get(jobID); ok {
+			status := "queued"
+			if job.NotBefore.After(time.Now()) {
+				status = "scheduled"
+			}
+			return SeenEntry{Status: status, FirstSeen: job.CreatedAt, UpdatedAt: job.CreatedAt}, true
+		}
+	}
+	return SeenEntry{}, false
+}
+
+// Kick retries a printer's queued jobs now, e.g. when it comes back online
+func (s *Spooler) Kick(printerID string) {
+	s.mu.Lock()
+	w, ok := s.workers[printerID]
+	s.mu.Unlock()
+	if ok {
+		signal(w.kick)
+	}
+}
+
+// Pause stops a printer's worker from starting new jobs; they wait in its queue.
+// A job already printing finishes.
+func (s *Spooler) Pause(printerID string) {
+	s.worker(printerID).setPaused(true)
+}
+
+// Resume restarts a paused printer's worker
+func (s *Spooler) Resume(printerID string) {
+	s.worker(printerID).setPaused(false)
+}
+
+// Paused reports whether a printer is paused
+func (s *Spooler) Paused(printerID string) bool {
+	s.mu.Lock()
+	w, ok := s.workers[printerID]
+	s.mu.Unlock()
+	return ok && w.isPaused()
+}
+
+// Depth returns the number of jobs waiting or printing for a printer
+func (s *Spooler) Depth(printerID string) int {
+	s.mu.Lock()
+	w, ok := s.workers[printerID]
+	s.mu.Unlock()
+	if !ok {
+		return 0
+	}
+	return w.depth()
+}
+
+// Job returns a job that is waiting or printing
+func (s *Spooler) Job(jobID string) (Job, bool) {
+	if s.queue != nil {
+		return s.queue.Get(jobID)
+	}
+	s.mu.Lock()
+	workers := make([]*worker, 0, len(s.workers))
+	for _, w := range s.workers {
+		workers = append(workers, w)
+	}
+	s.mu.Unlock()
+	for _, w := range workers {
+		for _, it := range w.items() {
+			if it.job.ID == jobID {
+				return it.job, true
+			}
+		}
+	}
+	return Job{}, false
+}
+
+// Pending returns the jobs waiting in the persistent queue, oldest first
+func (s *Spooler) Pending() []Job {
+	if s.queue == nil {
+		return nil
+	}
+	return s.queue.Pending()
+}
+
+// enqueue adds a job to its printer's worker, or to the scheduler if it is not due yet.
+// Returns true if the job was scheduled.
+func (s *Spooler) enqueue(job Job) bool {
+	s.mu.Lock()
+	s.seq++
+	it := &item{job: job, seq: s.seq}
+	s.mu.Unlock()
+
+	if job.NotBefore.After(time.Now()) {
+		s.sched.add(it)
+		return true
+	}
+	s.dispatch(it)
+	return false
+}
+
+// dispatch hands an item to its printer's worker
+func (s *Spooler) dispatch(it *item) {
+	w := s.worker(it.job.PrinterID)
+	if w.isPaused() {
+		s.emit(it.job, "held", "printer paused")
+	} else {
+		s.emit(it.job, "queued", "")
+	}
+	w.push(it)
+}
+
+// worker returns a printer's worker, creating and starting it if needed
+func (s *Spooler) worker(printerID string) *worker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.workers[printerID]
+	if !ok {
+		w = newWorker(printerID)
+		s.workers[printerID] = w
+		if s.started {
+			go s.run(w)
+		}
+	}
+	return w
+}
+
+// schedulerRecheck caps how long the scheduler sleeps, so a wall clock change (NTP
+// correction, suspend and resume) delays a scheduled job by a minute at most
+const schedulerRecheck = time.Minute
+
+// runScheduler releases scheduled jobs to their printers when they are due.
+// Released jobs keep their arrival order relative to other jobs of the same priority.
+func (s *Spooler) runScheduler() {
+	for {
+		ready, next := s.sched.due(time.Now())
+		for _, it := range ready {
+			log.Printf("Releasing scheduled job %s to printer %s", it.job.ID, it.job.PrinterID)
+			s.dispatch(it)
+		}
+
+		var timer <-chan time.Time
+		if next > 0 {
+			timer = time.After(min(next, schedulerRecheck))
+		}
+		select {
+		case <-s.done:
+			return
+		case <-s.sched.wake:
+		case <-timer:
+		}
+	}
+}
+
+// run is a printer worker's loop
+func (s *Spooler) run(w *worker) {
+	for {
+		it := w.pop()
+		if it == nil {
+			// Jobs waiting behind a paused printer still become overdue or expire
+			var check <-chan time.Time
+			if w.depth() > 0 {
+				check = time.After(s.checkInterval)
+			}
+			select {
+			case <-s.done:
+				return
+			case <-w.wake:
+			case <-check:
+				s.checkOverdue(w)
+				s.expireStale(w)
+			}
+			continue
+		}
+
+		wait, retry := s.process(it)
+		if !retry {
+			w.done()
+			continue
+		}
+
+		// Put the job back in its place and wait before trying the printer again
+		w.push(it)
+		w.done()
+		s.checkOverdue(w)
+		s.expireStale(w)
+
+		select {
+		case <-s.done:
+			return
+		case <-w.kick:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// process prints a job. If it must be tried again, returns retry=true and how long to wait.
+func (s *Spooler) process(it *item) (wait time.Duration, retry bool) {
+	if s.expired(it.job) {
+		s.finish(it.job, "expired", fmt.Sprintf("not printed within %s", s.expireAfter))
+		return 0, false
+	}
+	if it.held {
+		// Don't dial printers the health monitor already knows are down
+		if h := s.printers.Health(it.job.PrinterID); !h.CheckedAt.IsZero() && !printer.IsUp(h.Status) {
+			return s.retryInterval, true
+		}
+	}
+
+	now := time.Now()
+	it.job.Timing.DequeuedAt = &now
+	it.job.Timing.ConnectedAt = nil
+	it.job.Timing.WrittenAt = nil
+	if !it.dispatched {
+		it.dispatched = true
+		s.emit(it.job, "dispatched", "")
+	}
+
+	err := s.printCopies(it)
+	job := it.job
+	if err == nil {
+		s.finish(job, "completed", "")
+		return 0, false
+	}
+	if it.cancelled.Load() {
+		s.finish(it.job, "cancelled", cancelNote(it.job, err))
+		return 0, false
+	}
+	if it.held {
+		if errors.Is(err, printer.ErrConnect) {
+			return s.retryInterval, true
+		}
+		// The printer answers again but the print failed otherwise: apply the policy afresh
+		it.held = false
+		it.failures = 0
+	}
+
+	it.failures++
+	policy := s.policy(job.PrinterID)
+	if policy.Retryable(err) && it.failures < policy.MaxAttempts {
+		wait = policy.Backoff(it.failures)
+		s.emit(job, "retrying", fmt.Sprintf("attempt %d of %d failed: %v; retrying in %s",
+			it.failures, policy.MaxAttempts, err, wait))
+		return wait, true
+	}
+
+	// Out of retries. An unreachable printer keeps the job queued until it is back.
+	if errors.Is(err, printer.ErrConnect) {
+		it.held = true
+		it.job.LastError = err.Error()
+		if s.queue != nil {
+			if err := s.queue.Update(it.job); err != nil {
+				log.Printf("Failed to update queued job %s: %v", job.ID, err)
+			}
+		}
+		s.emit(it.job, "held", err.Error())
+		return s.retryInterval, true
+	}
+
+	s.finish(it.job, "failed", err.Error())
+	return 0, false
+}
+
+// printCopies prints the copies of a job not printed yet, stopping at the first error.
+// Progress is saved after each copy so a retry or restart resumes with the next one.
+func (s *Spooler) printCopies(it *item) error {
+	copies := it.job.Options.CopyCount()
+	total := 0
+	for n := 0; n < copies; n++ {
+		total += it.job.Options.copySize(len(it.job.Data), n)
+	}
+	var lastReport time.Time
+
+	for it.job.CopiesPrinted < copies {
+		if it.cancelled.Load() {
+			return errCancelled
+		}
+		done := 0
+		for n := 0; n < it.job.CopiesPrinted; n++ {
+			done += it.job.Options.copySize(len(it.job.Data), n)
+		}
+
+		// "sending" is reported off the print path, since reporting writes to disk and the
+		// printer is held while it runs. It is waited for before the job's next status.
+		var sending chan struct{}
+		data := it.job.Options.CopyData(it.job.Data, it.job.CopiesPrinted)
+		err := s.printers.PrintTraced(it.job.PrinterID, data, &printer.PrintTrace{
+			Connected: func() {
+				if it.job.Timing.ConnectedAt == nil {
+					now := time.Now()
+					it.job.Timing.ConnectedAt = &now
+					sending = make(chan struct{})
+					go func(job Job) {
+						s.emit(job, "sending", "")
+						close(sending)
+					}(it.job)
+				}
+			},
+			Cancelled: it.cancelled.Load,
+			Progress: func(written, _ int) {
+				// Throttled; the last chunk of a job is always reported
+				sent := done + written
+				if s.OnProgress != nil && (sent == total || time.Since(lastReport) >= progressInterval) {
+					lastReport = time.Now()
+					s.OnProgress(it.job, sent, total)
+				}
+			},
+		})
+		if sending != nil {
+			<-sending
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		it.job.Timing.WrittenAt = &now
+
+		// Each copy gets the full retry policy
+		it.failures = 0
+		it.job.CopiesPrinted++
+		if copies > 1 {
+			if s.queue != nil && it.job.CopiesPrinted < copies {
+				if err := s.queue.Update(it.job); err != nil {
+					log.Printf("Failed to update queued job %s: %v", it.job.ID, err)
+				}
+			}
+			if s.OnCopyPrinted != nil {
+				s.OnCopyPrinted(it.job)
+			}
+		}
+	}
+	return nil
+}
+
+// errCancelled stops printCopies when the job was cancelled between copies
+var errCancelled = errors.New("job cancelled")
+
+// cancelNote describes how far a cancelled job got
+func cancelNote(job Job, err error) string {
+	if errors.Is(err, printer.ErrCancelled) {
+		return fmt.Sprintf("cut short while printing copy %d of %d", job.CopiesPrinted+1, job.Options.CopyCount())
+	}
+	if job.CopiesPrinted == 0 {
+		return ""
+	}
+	return fmt.Sprintf("cancelled after %d of %d copies", job.CopiesPrinted, job.Options.CopyCount())
+}
+
+// finish removes a job from the queue and reports its final status
+func (s *Spooler) finish(job Job, status, errMsg string) {
+	if s.queue != nil {
+		if err := s.queue.Remove(job.ID); err != nil {
+			log.Printf("Failed to remove job %s from queue: %v", job.ID, err)
+		}
+	}
+	s.mu.Lock()
+	delete(s.warned, job.ID)
+	s.mu.Unlock()
+
+	s.emit(job, status, errMsg)
+}
+
+// policy returns the retry policy for a printer
+func (s *Spooler) policy(printerID string) RetryPolicy {
+	if s.Policy == nil {
+		return DefaultRetryPolicy()
+	}
+	return s.Policy(printerID)
+}
+
+// emit records a status change and reports it to OnStatus and any waiter
+func (s *Spooler) emit(job Job, status, errMsg string) {
+	if s.seen != nil {
+		s.seen.Update(job.ID, status, errMsg)
+	}
+
+	switch status {
+	case "queued", "dispatched", "sending", "retrying":
+	default:
+		s.mu.Lock()
+		ch, ok := s.waiters[job.ID]
+		delete(s.waiters, job.ID)
+		s.mu.Unlock()
+		if ok {
+			ch <- jobResult{status: status, errMsg: errMsg}
+		}
+	}
+
+	if s.OnStatus != nil {
+		s.OnStatus(job, status, errMsg)
+	}
+}
+
+// expired reports whether a job has waited past expireAfter
+func (s *Spooler) expired(job Job) bool {
+	return s.expireAfter > 0 && time.Since(job.readyAt()) > s.expireAfter
+}
+
+// expireStale drops a worker's waiting jobs that have expired
+func (s *Spooler) expireStale(w *worker) {
+	if s.expireAfter <= 0 {
+		return
+	}
+	for _, it := range w.items() {
+		if !s.expired(it.job) {
+			continue
+		}
+		if removed, _ := w.remove(it.job.ID); removed != nil {
+			s.finish(removed.job, "expired", fmt.Sprintf("not printed within %s", s.expireAfter))
+		}
+	}
+}
+
+// checkOverdue reports jobs waiting longer than the retention, once each
+func (s *Spooler) checkOverdue(w *worker) {
+	if s.retention <= 0 || s.OnOverdue == nil {
+		return
+	}
+	for _, it := range w.items() {
+		age := time.Since(it.job.readyAt())
+		if age <= s.retention {
+			continue
+		}
+		s.mu.Lock()
+		already := s.warned[it.job.ID]
+		s.warned[it.job.ID] = true
+		s.mu.Unlock()
+		if !already {
+			s.OnOverdue(it.job, age)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package jobs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jetsetgo/local-print-server/internal/printer"
+)
+
+// fakePrinter records what it prints. fail, if set, is called with the attempt
+// number (from 1) and may return an error in place of printing.
+type fakePrinter struct {
+	id    string
+	chunk int           // Bytes written per chunk; 0 writes the whole print at once
+	gate  chan struct{} // If set, each chunk waits for a value
+
+	mu       sync.Mutex
+	fail     func(attempt int) error
+	attempts int
+	printed  []string
+}
+
+func (p *fakePrinter) ID() string              { return p.id }
+func (p *fakePrinter) Name() string            { return p.id }
+func (p *fakePrinter) Type() string            { return "fake" }
+func (p *fakePrinter) Status() string          { return printer.StatusOnline }
+func (p *fakePrinter) Close() error            { return nil }
+func (p *fakePrinter) Print(data []byte) error { return p.PrintTraced(data, nil) }
+
+func (p *fakePrinter) PrintTraced(data []byte, trace *printer.PrintTrace) error {
+	p.mu.Lock()
+	p.attempts++
+	attempt, fail := p.attempts, p.fail
+	p.mu.Unlock()
+	if fail != nil {
+		if err := fail(attempt); err != nil {
+			return err
+		}
+	}
+	if trace != nil && trace.Connected != nil {
+		trace.Connected()
+	}
+
+	chunk := p.chunk
+	if chunk <= 0 {
+		chunk = len(data)
+	}
+	for written := 0; written < len(data); written += chunk {
+		if trace != nil && trace.Cancelled != nil && trace.Cancelled() {
+			return printer.ErrCancelled
+		}
+		if p.gate != nil {
+			<-p.gate
+		}
+		if trace != nil && trace.Progress != nil {
+			trace.Progress(min(written+chunk, len(data)), len(data))
+		}
+	}
+
+	p.mu.Lock()
+	p.printed = append(p.printed, string(data))
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *fakePrinter) prints() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.printed...)
+}
+
+func (p *fakePrinter) setFail(fail func(attempt int) error) {
+	p.mu.Lock()
+	p.fail = fail
+	p.mu.Unlock()
+}
+
+// statusLog collects the statuses a spooler reports
+type statusLog struct {
+	mu       sync.Mutex
+	statuses map[string][]string
+}
+
+func (l *statusLog) record(job Job, status, _ string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.statuses[job.ID] = append(l.statuses[job.ID], status)
+}
+
+func (l *statusLog) of(jobID string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.statuses[jobID]...)
+}
+
+// wait waits for a job to report the given status
+func (l *statusLog) wait(t *testing.T, jobID, status string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, s := range l.of(jobID) {
+			if s == status {
+				return
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s never reported %s; got %v", jobID, status, l.of(jobID))
+}
+
+// newTestSpooler returns an unstarted spooler for one fake printer, "p1"
+func newTestSpooler(queue *Queue, seen *SeenIndex) (*Spooler, *fakePrinter, *statusLog) {
+	p := &fakePrinter{id: "p1"}
+	printers := printer.NewManager()
+	printers.AddPrinter(p)
+
+	s := NewSpooler(queue, seen, printers)
+	log := &statusLog{statuses: make(map[string][]string)}
+	s.OnStatus = log.record
+	s.Policy = func(string) RetryPolicy {
+		return RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+			RetryOn:        []string{RetryOnConnect, RetryOnWrite},
+		}
+	}
+	return s, p, log
+}
+
+func TestSpoolerReportsOverdueWhilePaused(t *testing.T) {
+	s, _, _ := newTestSpooler(nil, nil)
+	overdue := make(chan string, 2)
+	s.OnOverdue = func(job Job, _ time.Duration) { overdue <- job.ID }
+	s.checkInterval = 10 * time.Millisecond
+	s.Pause("p1")
+	s.Start(time.Millisecond, time.Minute, 0)
+	defer s.Stop()
+
+	// One job queued long ago, one scheduled for a time that has passed
+	s.Submit(Job{ID: "old", PrinterID: "p1", Data: []byte("a"), CreatedAt: time.Now().Add(-time.Hour)})
+	s.Submit(Job{ID: "scheduled", PrinterID: "p1", Data: []byte("b"),
+		CreatedAt: time.Now().Add(-2 * time.Hour), NotBefore: time.Now().Add(-time.Hour)})
+
+	got := map[string]bool{}
+	for len(got) < 2 {
+		select {
+		case id := <-overdue:
+			got[id] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("overdue reported for %v, want old and scheduled", got)
+		}
+	}
+}
+
+func TestSpoolerPrintsByPriority(t *testing.T) {
+	s, p, statuses := newTestSpooler(nil, nil)
+	s.Pause("p1")
+	s.Start(time.Millisecond, 0, 0)
+	defer s.Stop()
+
+	for _, job := range []Job{
+		{ID: "a", Data: []byte("a")},
+		{ID: "b", Data: []byte("b")},
+		{ID: "c", Data: []byte("c"), Priority: 5},
+		{ID: "d", Data: []byte("d"), Priority: 1},
+	} {
+		job.PrinterID = "p1"
+		job.CreatedAt = time.Now()
+		s.Submit(job)
+	}
+	s.Resume("p1")
+	statuses.wait(t, "b", "completed")
+
+	if got := strings.Join(p.prints(), ""); got != "cdab" {
+		t.Errorf("printed %q, want cdab", got)
+	}
+}
+
+func TestSpoolerRetriesEachCopy(t *testing.T) {
+	s, p, statuses := newTestSpooler(nil, nil)
+	// The first attempt at every copy fails; two attempts per copy are allowed
+	p.setFail(func(attempt int) error {
+		if attempt%2 == 1 {
+			return fmt.Errorf("%w: connection reset", printer.ErrWrite)
+		}
+		return nil
+	})
+	s.Start(time.Millisecond, 0, 0)
+	defer s.Stop()
+
+	s.Submit(Job{ID: "x", PrinterID: "p1", Data: []byte("x"), CreatedAt: time.Now(), Options: Options{Copies: 3}})
+	statuses.wait(t, "x", "completed")
+
+	if n := len(p.prints()); n != 3 {
+		t.Errorf("printed %d copies, want 3", n)
+	}
+	retries := 0
+	for _, status := range statuses.of("x") {
+		if status == "retrying" {
+			retries++
+		}
+	}
+	if retries != 3 {
+		t.Errorf("statuses %v, want one retry per copy", statuses.of("x"))
+	}
+}
+
+func TestSpoolerResumesHeldJob(t *testing.T) {
+	s, p, statuses := newTestSpooler(nil, nil)
+	p.setFail(func(int) error { return fmt.Errorf("%w: refused", printer.ErrConnect) })
+	s.Start(time.Hour, 0, 0) // Only a kick retries the held job
+	defer s.Stop()
+
+	s.Submit(Job{ID: "x", PrinterID: "p1", Data: []byte("x"), CreatedAt: time.Now()})
+	statuses.wait(t, "x", "held")
+
+	p.setFail(nil)
+	s.Kick("p1")
+	statuses.wait(t, "x", "completed")
+	if got := p.prints(); len(got) != 1 {
+		t.Errorf("printed %v, want one copy", got)
+	}
+}
+
+func TestSpoolerCancelsBetweenChunks(t *testing.T) {
+	s, p, statuses := newTestSpooler(nil, nil)
+	p.chunk = 1
+	p.gate = make(chan struct{})
+	s.Start(time.Millisecond, 0, 0)
+	defer s.Stop()
+
+	s.Submit(Job{ID: "x", PrinterID: "p1", Data: []byte("abcd"), CreatedAt: time.Now()})
+	statuses.wait(t, "x", "sending")
+
+	inFlight, err := s.Cancel("x")
+	if err != nil || !inFlight {
+		t.Fatalf("Cancel = %v, %v; want in flight", inFlight, err)
+	}
+	p.gate <- struct{}{} // Let the first chunk through; the next one sees the cancel
+	statuses.wait(t, "x", "cancelled")
+
+	if got := p.prints(); len(got) != 0 {
+		t.Errorf("printed %v after cancelling", got)
+	}
+	for _, status := range statuses.of("x") {
+		if status == "completed" {
+			t.Errorf("statuses %v, want no completed", statuses.of("x"))
+		}
+	}
+}
+
+func TestSpoolerSuppressesDuplicatesAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+	open := func() (*Queue, *SeenIndex) {
+		t.Helper()
+		q, err := OpenQueue(filepath.Join(dir, "queue"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen, err := OpenSeenIndex(filepath.Join(dir, "seen.jsonl"), time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return q, seen
+	}
+	job := Job{ID: "x", PrinterID: "p1", Data: []byte("x"), CreatedAt: time.Now()}
+
+	s, _, statuses := newTestSpooler(open())
+	s.Start(time.Millisecond, 0, 0)
+	if _, accepted := s.Submit(job); !accepted {
+		t.Fatal("first delivery not accepted")
+	}
+	statuses.wait(t, "x", "completed")
+	s.Stop()
+
+	s, p, _ := newTestSpooler(open())
+	s.Start(time.Millisecond, 0, 0)
+	defer s.Stop()
+	prev, accepted := s.Submit(job)
+	if accepted || prev.Status != "completed" {
+		t.Errorf("redelivery = %+v, %v; want it refused as completed", prev, accepted)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := p.prints(); len(got) != 0 {
+		t.Errorf("redelivered job printed %v", got)
+	}
+}
+
+func TestSpoolerReplaysQueuedJobsOnce(t *testing.T) {
+	q, err := OpenQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Put(Job{ID: "old", PrinterID: "p1", Data: []byte("o"), CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, p, statuses := newTestSpooler(q, nil)
+	// Submitted before Start, so it is in the queue when Start replays it
+	s.Submit(Job{ID: "new", PrinterID: "p1", Data: []byte("n"), CreatedAt: time.Now()})
+	s.Start(time.Millisecond, 0, 0)
+	defer s.Stop()
+
+	statuses.wait(t, "old", "completed")
+	statuses.wait(t, "new", "completed")
+	time.Sleep(20 * time.Millisecond)
+	if got := strings.Join(p.prints(), ""); len(got) != 2 {
+		t.Errorf("printed %q, want each job once", got)
+	}
+}
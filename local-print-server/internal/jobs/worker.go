@@ -0,0 +1,150 @@
+package jobs
+
+import (
+	"container/heap"
+	"sync"
+	"sync/atomic"
+)
+
+// item is a job waiting in a printer's queue
+type item struct {
+	job        Job
+	seq        uint64 // Arrival order, for FIFO within a priority level
+	failures   int    // Failed attempts at the current copy
+	dispatched bool   // Set once "dispatched" has been reported
+	held       bool   // Set while the job is reported as held because the printer is down
+
+	cancelled atomic.Bool // Set when the job is cancelled while printing
+}
+
+// jobHeap orders items by priority (highest first), then arrival order
+type jobHeap []*item
+
+func (h jobHeap) Len() int { return len(h) }
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].job.Priority != h[j].job.Priority {
+		return h[i].job.Priority > h[j].job.Priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h jobHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *jobHeap) Push(x any)   { *h = append(*h, x.(*item)) }
+func (h *jobHeap) Pop() any {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	*h = old[:n-1]
+	return it
+}
+
+// worker prints the jobs for a single printer, one at a time
+type worker struct {
+	printerID string
+
+	mu      sync.Mutex
+	queue   jobHeap
+	current *item // Item being printed, if any
+	paused  bool  // Jobs wait in the queue until resumed
+
+	wake chan struct{} // A job was added
+	kick chan struct{} // The printer may be back; retry now
+}
+
+func newWorker(printerID string) *worker {
+	return &worker{
+		printerID: printerID,
+		wake:      make(chan struct{}, 1),
+		kick:      make(chan struct{}, 1),
+	}
+}
+
+// push adds an item to the queue and wakes the worker
+func (w *worker) push(it *item) {
+	w.mu.Lock()
+	heap.Push(&w.queue, it)
+	w.mu.Unlock()
+	signal(w.wake)
+}
+
+// pop removes the next item, or returns nil if the queue is empty or paused
+func (w *worker) pop() *item {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.queue) == 0 || w.paused {
+		return nil
+	}
+	w.current = heap.Pop(&w.queue).(*item)
+	return w.current
+}
+
+// setPaused pauses or resumes the worker. A job being printed is not interrupted.
+func (w *worker) setPaused(paused bool) {
+	w.mu.Lock()
+	w.paused = paused
+	w.mu.Unlock()
+	if !paused {
+		signal(w.wake)
+		signal(w.kick)
+	}
+}
+
+// isPaused reports whether the worker is paused
+func (w *worker) isPaused() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.paused
+}
+
+// done marks the current item finished
+func (w *worker) done() {
+	w.mu.Lock()
+	w.current = nil
+	w.mu.Unlock()
+}
+
+// remove takes a waiting job out of the queue. If the job is being printed instead,
+// it is flagged as cancelled and inFlight is true.
+func (w *worker) remove(jobID string) (it *item, inFlight bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i, queued := range w.queue {
+		if queued.job.ID == jobID {
+			heap.Remove(&w.queue, i)
+			return queued, false
+		}
+	}
+	if w.current != nil && w.current.job.ID == jobID {
+		w.current.cancelled.Store(true)
+		return w.current, true
+	}
+	return nil, false
+}
+
+// depth returns the number of jobs waiting or printing
+func (w *worker) depth() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(w.queue)
+	if w.current != nil {
+		n++
+	}
+	return n
+}
+
+// items returns a snapshot of the waiting items
+func (w *worker) items() []*item {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]*item(nil), w.queue...)
+}
+
+// signal does a non-blocking send on a 1-buffered channel
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
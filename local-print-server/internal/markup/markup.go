@@ -0,0 +1,209 @@
+// Package markup converts plain text with lightweight tags into ESC/POS, so callers
+// can print formatted text without building printer commands themselves.
+//
+// Tags are written in braces. Style tags switch formatting on and off and carry over
+// line breaks until closed:
+//
+//	{b}bold{/b}  {u}underline{/u}  {big}double size{/big}  {wide}...{/wide}  {tall}...{/tall}
+//
+// Alignment tags apply from the next line that starts after them:
+//
+//	{center}  {right}  {left}  ({/center} and {/right} return to left)
+//
+// Block tags print on their own:
+//
+//	{barcode CODE128 A-1234}  {qr https://example.com}  {hr}  {hr =}  {feed 3}  {cut}
+//
+// Write {{ for a literal brace. A line holding only tags prints nothing; an empty
+// line prints a blank line.
+package markup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jetsetgo/local-print-server/internal/escpos"
+)
+
+// Options describe the target printer
+type Options struct {
+	Profile  escpos.Profile
+	CodePage string // "" for escpos.DefaultCodePage
+	Columns  int    // Font A characters per line
+}
+
+// converter holds the printer state while rendering
+type converter struct {
+	opts Options
+	b    *escpos.Builder
+
+	bold, underline bool
+	width, height   int
+	align           escpos.Align // Alignment for lines starting from now
+	lineAlign       escpos.Align // Alignment last sent with ESC a
+
+	lineText bool // The current line has printed text
+}
+
+// Render converts markup to ESC/POS. Errors name the offending line.
+func Render(text string, opts Options) ([]byte, error) {
+	if opts.Columns <= 0 {
+		opts.Columns = 48
+	}
+	c := &converter{opts: opts, b: escpos.NewBuilder(opts.Profile), width: 1, height: 1}
+	if err := c.b.Init().CodePage(opts.CodePage).Err(); err != nil {
+		return nil, err
+	}
+
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range strings.Split(text, "\n") {
+		if err := c.line(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return c.b.Bytes(), nil
+}
+
+// line renders one line of markup
+func (c *converter) line(line string) error {
+	c.lineText = false
+	blank := line == ""
+
+	for line != "" {
+		i := strings.IndexByte(line, '{')
+		if i < 0 {
+			c.text(line)
+			break
+		}
+		if i > 0 {
+			c.text(line[:i])
+		}
+		if strings.HasPrefix(line[i:], "{{") {
+			c.text("{")
+			line = line[i+2:]
+			continue
+		}
+		end := strings.IndexByte(line[i:], '}')
+		if end < 0 {
+			return fmt.Errorf("unclosed tag %q", line[i:])
+		}
+		if err := c.tag(line[i+1 : i+end]); err != nil {
+			return err
+		}
+		line = line[i+end+1:]
+	}
+
+	if c.lineText || blank {
+		c.b.Newline()
+	}
+	return c.b.Err()
+}
+
+// text prints characters on the current line
+func (c *converter) text(s string) {
+	if s == "" {
+		return
+	}
+	c.startLine()
+	c.lineText = true
+	c.b.Text(s)
+}
+
+// startLine sends the alignment before the first thing printed on a line
+func (c *converter) startLine() {
+	if !c.lineText && c.align != c.lineAlign {
+		c.b.Align(c.align)
+		c.lineAlign = c.align
+	}
+}
+
+// endLine finishes a line of text before a block
+func (c *converter) endLine() {
+	if c.lineText {
+		c.b.Newline()
+		c.lineText = false
+	}
+}
+
+// tag applies one tag, given without its braces
+func (c *converter) tag(tag string) error {
+	name, arg, _ := strings.Cut(strings.TrimSpace(tag), " ")
+	name, arg = strings.ToLower(name), strings.TrimSpace(arg)
+
+	switch name {
+	case "b", "/b":
+		c.bold = name == "b"
+		c.b.Bold(c.bold)
+	case "u", "/u":
+		c.underline = name == "u"
+		c.b.Underline(c.underline)
+	case "big", "/big":
+		c.setSize(name == "big", name == "big")
+	case "wide", "/wide":
+		c.setSize(name == "wide", c.height == 2)
+	case "tall", "/tall":
+		c.setSize(c.width == 2, name == "tall")
+	case "left", "/center", "/right":
+		c.align = escpos.AlignLeft
+	case "center":
+		c.align = escpos.AlignCenter
+	case "right":
+		c.align = escpos.AlignRight
+	case "hr":
+		char := "-"
+		if arg != "" {
+			char = string([]rune(arg)[0])
+		}
+		c.endLine()
+		c.text(strings.Repeat(char, c.opts.Columns/c.width))
+		c.endLine()
+	case "feed":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > 255 {
+			return fmt.Errorf("feed needs a number of lines from 1 to 255")
+		}
+		c.endLine()
+		c.b.Feed(n)
+	case "cut":
+		c.endLine()
+		c.b.Cut()
+	case "barcode":
+		return c.barcode(arg)
+	case "qr":
+		return c.qr(arg)
+	default:
+		return fmt.Errorf("unknown tag {%s}", tag)
+	}
+	return nil
+}
+
+func (c *converter) setSize(wide, tall bool) {
+	c.width, c.height = 1, 1
+	if wide {
+		c.width = 2
+	}
+	if tall {
+		c.height = 2
+	}
+	c.b.Size(c.width, c.height)
+}
+
+// barcode prints a 1D barcode with its text below: {barcode TYPE DATA}
+func (c *converter) barcode(arg string) error {
+	kind, data, _ := strings.Cut(arg, " ")
+	sym, ok := escpos.ParseSymbology(kind)
+	if !ok {
+		return fmt.Errorf("unknown barcode type %q (use CODE128, CODE39, EAN13, EAN8, UPCA or ITF)", kind)
+	}
+	c.endLine()
+	c.startLine()
+	return c.b.Barcode(sym, strings.TrimSpace(data)).Err()
+}
+
+// qr prints a QR code: {qr DATA}. Printers without QR support print the data instead.
+func (c *converter) qr(data string) error {
+	c.endLine()
+	c.startLine()
+	return c.b.QRCode(data).Err()
+}
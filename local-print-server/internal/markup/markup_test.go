@@ -0,0 +1,97 @@
+package markup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jetsetgo/local-print-server/internal/escpos"
+)
+
+// header is what every render starts with: ESC @ and the cp437 code page
+var header = []byte("\x1b@\x1bt\x00")
+
+func render(t *testing.T, text string, opts Options) []byte {
+	t.Helper()
+	out, err := Render(text, opts)
+	if err != nil {
+		t.Fatalf("Render(%q): %v", text, err)
+	}
+	if !bytes.HasPrefix(out, header) {
+		t.Fatalf("output %q does not start with the init header", out)
+	}
+	return out[len(header):]
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "hello", "hello\n"},
+		{"bold", "a {b}b{/b} c", "a \x1bE\x01b\x1bE\x00 c\n"},
+		{"literal brace", "{{b}", "{b}\n"},
+		{"align next line", "{center}\nmid\n{left}x", "\x1ba\x01mid\n\x1ba\x00x\n"},
+		{"tags only", "{b}\n\nx", "\x1bE\x01\nx\n"},
+		{"big", "{big}X{/big}", "\x1d!\x11X\x1d!\x00\n"},
+		{"wide then tall", "{wide}{tall}X", "\x1d!\x10\x1d!\x11X\n"},
+		{"hr", "{hr =}", "==========\n"},
+		{"feed and cut", "a{feed 2}{cut}", "a\n\x1bd\x02\x1dVB\x00"},
+		{"crlf", "a\r\nb\r\n", "a\nb\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := render(t, tt.text, Options{Columns: 10})
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderBlocks(t *testing.T) {
+	epson, _ := escpos.LookupProfile("epson")
+	out := render(t, "{right}total {barcode CODE128 A{1}", Options{Profile: epson})
+	if !strings.HasPrefix(string(out), "\x1ba\x02total \n\x1dH") {
+		t.Errorf("barcode does not start a new line: %q", out)
+	}
+	if !bytes.Contains(out, []byte("{BA{{1")) {
+		t.Errorf("barcode data missing from %q", out)
+	}
+
+	// QR codes print as text without profile support
+	out = render(t, "{qr https://example.com}", Options{})
+	if string(out) != "https://example.com\n" {
+		t.Errorf("generic qr = %q, want the data as text", out)
+	}
+	out = render(t, "{qr https://example.com}", Options{Profile: epson})
+	if !bytes.Contains(out, []byte("\x1d(k")) {
+		t.Errorf("epson qr = %q, want GS ( k", out)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"ok\n{b", "line 2: unclosed tag"},
+		{"{blink}", "line 1: unknown tag {blink}"},
+		{"{feed 0}", "feed needs a number"},
+		{"{feed x}", "feed needs a number"},
+		{"{barcode QR123 x}", "unknown barcode type"},
+		{"{barcode EAN13 12}", "line 1:"},
+		{"{qr}", "qr needs data"},
+	}
+	for _, tt := range tests {
+		_, err := Render(tt.text, Options{})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Render(%q) err = %v, want %q", tt.text, err, tt.want)
+		}
+	}
+
+	if _, err := Render("x", Options{CodePage: "cp1251"}); err == nil {
+		t.Error("unsupported code page was accepted")
+	}
+}
@@ -0,0 +1,94 @@
+// Package preview interprets ESC/POS data and renders it as it would come out of the
+// printer, as text, HTML or a PNG image. It understands the commands the print server
+// and common POS software send; anything else is skipped.
+package preview
+
+// Align is a line's justification
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// Style is the character formatting of a span of text
+type Style struct {
+	Bold      bool
+	Underline bool
+	Invert    bool // White on black (GS B)
+	Width     int  // Character width multiplier, 1-8
+	Height    int  // Character height multiplier, 1-8
+}
+
+// Span is a run of text in one style
+type Span struct {
+	Text  string
+	Style Style
+}
+
+// Element is one printed item: a line of text, an image, a barcode, a cut or a marker
+type Element interface {
+	element()
+}
+
+// Line is a line of text. An empty line is a blank feed.
+type Line struct {
+	Align Align
+	Spans []Span
+}
+
+// Image is a raster or bit image, one bool per dot (true = black), row by row
+type Image struct {
+	Align  Align
+	Width  int
+	Height int
+	Pix    []bool
+}
+
+// Barcode is a 1D or 2D symbol. It is previewed as a placeholder showing its content.
+type Barcode struct {
+	Align     Align
+	Symbology string // e.g. CODE128, EAN13, QR, PDF417
+	Data      string
+}
+
+// Cut is a paper cut
+type Cut struct {
+	Partial bool
+}
+
+// Marker is a non-printing action, e.g. a cash drawer kick or a beep
+type Marker struct {
+	Text string
+}
+
+func (Line) element()    {}
+func (Image) element()   {}
+func (Barcode) element() {}
+func (Cut) element()     {}
+func (Marker) element()  {}
+
+// Document is interpreted ESC/POS output for a paper width
+type Document struct {
+	Columns  int // Font A characters per line
+	Dots     int // Printable width in dots
+	Elements []Element
+}
+
+// Width returns the visible width of a line in Font A columns
+func (l Line) Width() int {
+	w := 0
+	for _, s := range l.Spans {
+		w += len([]rune(s.Text)) * s.Style.Width
+	}
+	return w
+}
+
+// paperGeometry returns the Font A columns and printable dots for a paper width in mm
+func paperGeometry(paperWidth int) (columns, dots int) {
+	if paperWidth == 58 {
+		return 32, 384
+	}
+	return 48, 576
+}
@@ -0,0 +1,438 @@
+package preview
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jetsetgo/local-print-server/internal/escpos"
+)
+
+// Control bytes
+const (
+	ht  = 0x09
+	lf  = 0x0A
+	ff  = 0x0C
+	cr  = 0x0D
+	dle = 0x10
+	esc = 0x1B
+	fs  = 0x1C
+	gs  = 0x1D
+)
+
+// lineDots is the default line spacing (ESC 2), used to turn dot feeds into lines
+const lineDots = 30
+
+// Largest image the preview decodes, in dots. Printers are at most a few hundred dots
+// wide; the limits stop a bad header from allocating gigabytes.
+const (
+	maxImageDots = 4096
+	maxImageRows = 8192
+)
+
+// escArgs and gsArgs are the argument counts of fixed-length commands that don't
+// affect the preview, so they can be skipped
+var escArgs = map[byte]int{
+	' ': 1, '%': 1, '2': 0, '3': 1, '<': 0, '=': 1, '$': 2, '\\': 2, 'L': 0, 'S': 0,
+	'M': 1, 'R': 1, 'T': 1, 'U': 1, 'V': 1, 'W': 8, 'r': 1, '{': 1, 'c': 2,
+}
+
+var gsArgs = map[byte]int{
+	'$': 2, '\\': 2, 'H': 1, 'I': 1, 'L': 2, 'P': 2, 'W': 2, 'a': 1, 'b': 1, 'f': 1,
+	'h': 1, 'r': 1, 'w': 1, 'x': 1, 'y': 1, ':': 0, '^': 3,
+}
+
+// barcodeNames maps GS k symbology numbers to names. 0-6 are the NUL-terminated
+// forms; 65 and up take a length byte.
+var barcodeNames = map[byte]string{
+	0: "UPC-A", 1: "UPC-E", 2: "EAN13", 3: "EAN8", 4: "CODE39", 5: "ITF", 6: "CODABAR",
+	65: "UPC-A", 66: "UPC-E", 67: "EAN13", 68: "EAN8", 69: "CODE39", 70: "ITF",
+	71: "CODABAR", 72: "CODE93", 73: "CODE128",
+}
+
+// generic is the profile whose ESC t numbering the preview follows
+var generic, _ = escpos.LookupProfile(escpos.DefaultProfile)
+
+// parser turns ESC/POS bytes into a Document
+type parser struct {
+	doc   *Document
+	data  []byte
+	pos   int
+	style Style
+	align Align
+
+	codePage string // Selected with ESC t, by the Epson numbering most printers use
+
+	line      Line // Line being built
+	lineWidth int  // Its width in Font A columns
+
+	symbols map[byte]string // 2D symbol data stored by GS ( k, by symbol type
+}
+
+// Parse interprets ESC/POS data for a paper width in mm (58 or 80)
+func Parse(data []byte, paperWidth int) *Document {
+	cols, dots := paperGeometry(paperWidth)
+	p := &parser{
+		doc:     &Document{Columns: cols, Dots: dots},
+		data:    data,
+		symbols: make(map[byte]string),
+	}
+	p.reset()
+	p.run()
+	if len(p.line.Spans) > 0 {
+		p.flush()
+	}
+	return p.doc
+}
+
+func (p *parser) reset() {
+	p.style = Style{Width: 1, Height: 1}
+	p.align = AlignLeft
+	p.codePage = escpos.DefaultCodePage
+}
+
+// next returns the next byte, or 0 and false at the end of the data
+func (p *parser) next() (byte, bool) {
+	if p.pos >= len(p.data) {
+		return 0, false
+	}
+	b := p.data[p.pos]
+	p.pos++
+	return b, true
+}
+
+// take returns the next n bytes, or as many as remain
+func (p *parser) take(n int) []byte {
+	end := min(p.pos+n, len(p.data))
+	b := p.data[p.pos:end]
+	p.pos = end
+	return b
+}
+
+// word reads a little-endian 16-bit argument
+func (p *parser) word() int {
+	b := p.take(2)
+	if len(b) < 2 {
+		return 0
+	}
+	return int(b[0]) | int(b[1])<<8
+}
+
+func (p *parser) run() {
+	for {
+		b, ok := p.next()
+		if !ok {
+			return
+		}
+		switch {
+		case b == lf || b == ff:
+			p.flush()
+		case b == ht:
+			p.text(strings.Repeat(" ", 8-p.lineWidth%8))
+		case b == esc:
+			p.esc()
+		case b == gs:
+			p.gs()
+		case b == fs:
+			p.fs()
+		case b == dle:
+			p.dle()
+		case b == cr || b < 0x20 || b == 0x7F:
+			// No visible effect
+		default:
+			p.text(string(escpos.Decode(b, p.codePage)))
+		}
+	}
+}
+
+// text adds characters to the current line, wrapping at the paper width
+func (p *parser) text(s string) {
+	for _, r := range s {
+		if p.lineWidth+p.style.Width > p.doc.Columns && p.lineWidth > 0 {
+			p.flush()
+		}
+		if len(p.line.Spans) == 0 {
+			p.line.Align = p.align
+		}
+		if n := len(p.line.Spans); n > 0 && p.line.Spans[n-1].Style == p.style {
+			p.line.Spans[n-1].Text += string(r)
+		} else {
+			p.line.Spans = append(p.line.Spans, Span{Text: string(r), Style: p.style})
+		}
+		p.lineWidth += p.style.Width
+	}
+}
+
+// flush ends the current line, as LF does. An empty line is a blank feed.
+func (p *parser) flush() {
+	if len(p.line.Spans) == 0 {
+		p.line.Align = p.align
+	}
+	p.doc.Elements = append(p.doc.Elements, p.line)
+	p.line = Line{}
+	p.lineWidth = 0
+}
+
+// endLine ends the current line if it has text, before a non-text element
+func (p *parser) endLine() {
+	if len(p.line.Spans) > 0 {
+		p.flush()
+	}
+}
+
+// feed prints the current line and feeds n lines in all
+func (p *parser) feed(n int) {
+	if len(p.line.Spans) > 0 {
+		p.flush()
+		n--
+	}
+	for ; n > 0; n-- {
+		p.flush()
+	}
+}
+
+func (p *parser) add(e Element) {
+	p.endLine()
+	p.doc.Elements = append(p.doc.Elements, e)
+}
+
+func (p *parser) esc() {
+	cmd, ok := p.next()
+	if !ok {
+		return
+	}
+	switch cmd {
+	case '@':
+		p.reset()
+	case 'E', 'G':
+		b, _ := p.next()
+		p.style.Bold = b&1 == 1
+	case '-':
+		b, _ := p.next()
+		p.style.Underline = b == 1 || b == 2 || b == '1' || b == '2'
+	case 'a':
+		b, _ := p.next()
+		p.align = Align(b & 3 % 3)
+	case '!':
+		b, _ := p.next()
+		p.style.Bold = b&0x08 != 0
+		p.style.Height = 1 + int(b>>4&1)
+		p.style.Width = 1 + int(b>>5&1)
+		p.style.Underline = b&0x80 != 0
+	case 'd':
+		n, _ := p.next()
+		p.feed(int(n))
+	case 'J':
+		n, _ := p.next()
+		p.feed(int(n) / lineDots)
+	case 'i', 'm':
+		p.add(Cut{Partial: cmd == 'm'})
+	case 'p':
+		p.take(3)
+		p.add(Marker{Text: "open cash drawer"})
+	case 'B':
+		b := p.take(2)
+		if len(b) == 2 {
+			p.add(Marker{Text: fmt.Sprintf("beep x%d", b[0])})
+		}
+	case '*':
+		p.bitImage()
+	case 't':
+		n, _ := p.next()
+		if name, ok := generic.CodePageName(n); ok {
+			p.codePage = name
+		}
+	case 'D':
+		// Tab stops, NUL-terminated
+		for {
+			if b, ok := p.next(); !ok || b == 0 {
+				break
+			}
+		}
+	default:
+		p.take(escArgs[cmd])
+	}
+}
+
+func (p *parser) gs() {
+	cmd, ok := p.next()
+	if !ok {
+		return
+	}
+	switch cmd {
+	case '!':
+		b, _ := p.next()
+		p.style.Width = 1 + int(b>>4&7)
+		p.style.Height = 1 + int(b&7)
+	case 'B':
+		b, _ := p.next()
+		p.style.Invert = b&1 == 1
+	case 'V':
+		m, _ := p.next()
+		if m >= 65 {
+			p.next() // Feed before cutting
+		}
+		p.add(Cut{Partial: m == 1 || m == 49 || m == 66})
+	case 'v':
+		if b, _ := p.next(); b == '0' {
+			p.next() // Scale mode
+			p.raster()
+		}
+	case 'k':
+		p.barcode()
+	case '(':
+		p.extended()
+	case '/':
+		p.next()
+		p.add(Marker{Text: "downloaded image"})
+	default:
+		p.take(gsArgs[cmd])
+	}
+}
+
+func (p *parser) fs() {
+	cmd, ok := p.next()
+	if !ok {
+		return
+	}
+	switch cmd {
+	case 'p':
+		p.take(2)
+		p.add(Marker{Text: "stored logo"})
+	case 'C', '!', '-', 'S', 'W':
+		p.take(1)
+	}
+}
+
+func (p *parser) dle() {
+	cmd, ok := p.next()
+	if !ok {
+		return
+	}
+	switch cmd {
+	case 0x04, 0x05: // DLE EOT n, DLE ENQ n
+		p.take(1)
+	case 0x14: // DLE DC4 fn a b
+		p.take(3)
+	}
+}
+
+// raster reads GS v 0 image data (after the mode byte)
+func (p *parser) raster() {
+	widthBytes := p.word()
+	height := p.word()
+	data := p.take(widthBytes * height)
+	if widthBytes == 0 {
+		return
+	}
+	if widthBytes*8 > maxImageDots || height > maxImageRows {
+		p.add(Marker{Text: fmt.Sprintf("image %dx%d too large to preview", widthBytes*8, height)})
+		return
+	}
+	// Truncated data: only the rows that are present
+	height = min(height, len(data)/widthBytes)
+	data = data[:widthBytes*height]
+
+	img := Image{Align: p.align, Width: widthBytes * 8, Height: height}
+	img.Pix = make([]bool, img.Width*img.Height)
+	for i, b := range data {
+		y, xb := i/widthBytes, i%widthBytes
+		for bit := 0; bit < 8; bit++ {
+			img.Pix[y*img.Width+xb*8+bit] = b&(0x80>>bit) != 0
+		}
+	}
+	p.add(img)
+}
+
+// bitImage reads an ESC * column-format image band
+func (p *parser) bitImage() {
+	m, _ := p.next()
+	n := p.word()
+
+	rows, scaleX := 8, 1
+	if m >= 32 {
+		rows = 24
+	}
+	if m == 0 || m == 32 {
+		scaleX = 2 // Double-density modes print each column twice as wide
+	}
+	data := p.take(n * rows / 8)
+	if n*scaleX > maxImageDots {
+		p.add(Marker{Text: fmt.Sprintf("image %dx%d too large to preview", n*scaleX, rows)})
+		return
+	}
+	// Truncated data: only the columns that are present
+	n = min(n, len(data)/(rows/8))
+	data = data[:n*rows/8]
+
+	img := Image{Align: p.align, Width: n * scaleX, Height: rows}
+	img.Pix = make([]bool, img.Width*img.Height)
+	for i, b := range data {
+		col, band := i/(rows/8), i%(rows/8)
+		for bit := 0; bit < 8; bit++ {
+			if b&(0x80>>bit) == 0 {
+				continue
+			}
+			y := band*8 + bit
+			for s := 0; s < scaleX; s++ {
+				img.Pix[y*img.Width+col*scaleX+s] = true
+			}
+		}
+	}
+	p.add(img)
+}
+
+// barcode reads a GS k 1D barcode
+func (p *parser) barcode() {
+	m, ok := p.next()
+	if !ok {
+		return
+	}
+	var data []byte
+	if m < 65 {
+		start := p.pos
+		for {
+			if b, ok := p.next(); !ok || b == 0 {
+				break
+			}
+		}
+		data = p.data[start:max(start, p.pos-1)]
+	} else {
+		n, _ := p.next()
+		data = p.take(int(n))
+	}
+
+	name, ok := barcodeNames[m]
+	if !ok {
+		name = fmt.Sprintf("barcode %d", m)
+	}
+	text := string(data)
+	if m == 73 && len(text) >= 2 && text[0] == '{' {
+		text = strings.ReplaceAll(text[2:], "{{", "{") // Code set selector and escaped braces
+	}
+	p.add(Barcode{Align: p.align, Symbology: name, Data: text})
+}
+
+// extended reads a GS ( command. QR codes and PDF417 symbols (GS ( k) are stored and
+// then printed by separate functions; other functions are skipped.
+func (p *parser) extended() {
+	fn, ok := p.next()
+	if !ok {
+		return
+	}
+	args := p.take(p.word())
+	if fn != 'k' || len(args) < 2 {
+		return
+	}
+
+	symbol, function := args[0], args[1]
+	switch {
+	case function == 80 && len(args) >= 3: // Store data: cn 80 m d1...dk
+		p.symbols[symbol] = string(args[3:])
+	case function == 81: // Print the stored symbol
+		name := "PDF417"
+		if symbol == 49 {
+			name = "QR"
+		}
+		p.add(Barcode{Align: p.align, Symbology: name, Data: p.symbols[symbol]})
+	}
+}
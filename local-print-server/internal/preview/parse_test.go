@@ -0,0 +1,127 @@
+package preview
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseText(t *testing.T) {
+	data := []byte("\x1b@\x1ba\x01\x1bE\x01TOTAL\x1bE\x00 9.99\n\x1dV\x42\x00")
+	doc := Parse(data, 80)
+
+	if len(doc.Elements) != 2 {
+		t.Fatalf("got %d elements, want 2: %#v", len(doc.Elements), doc.Elements)
+	}
+	line, ok := doc.Elements[0].(Line)
+	if !ok {
+		t.Fatalf("element 0 is %T, want Line", doc.Elements[0])
+	}
+	if line.Align != AlignCenter {
+		t.Errorf("align = %v, want center", line.Align)
+	}
+	if len(line.Spans) != 2 || line.Spans[0].Text != "TOTAL" || !line.Spans[0].Style.Bold || line.Spans[1].Text != " 9.99" {
+		t.Errorf("spans = %#v", line.Spans)
+	}
+	if cut, ok := doc.Elements[1].(Cut); !ok || !cut.Partial {
+		t.Errorf("element 1 = %#v, want partial cut", doc.Elements[1])
+	}
+}
+
+func TestParseWraps(t *testing.T) {
+	doc := Parse([]byte(strings.Repeat("x", 40)+"\n"), 58)
+	if len(doc.Elements) != 2 {
+		t.Fatalf("got %d lines, want 2", len(doc.Elements))
+	}
+	if w := doc.Elements[0].(Line).Width(); w != 32 {
+		t.Errorf("first line is %d columns, want 32", w)
+	}
+}
+
+func TestParseRaster(t *testing.T) {
+	// 2 bytes wide, 2 rows
+	doc := Parse([]byte{0x1d, 'v', '0', 0, 2, 0, 2, 0, 0x80, 0x01, 0x00, 0xff}, 80)
+	img := onlyImage(t, doc)
+	if img.Width != 16 || img.Height != 2 || len(img.Pix) != 32 {
+		t.Fatalf("image %dx%d with %d pixels, want 16x2", img.Width, img.Height, len(img.Pix))
+	}
+	if !img.Pix[0] || img.Pix[1] || !img.Pix[15] || img.Pix[16] || !img.Pix[31] {
+		t.Errorf("pixels = %v", img.Pix)
+	}
+}
+
+func TestParseBadImageHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		wantWidth  int // 0 means a marker instead of an image
+		wantHeight int
+	}{
+		{"huge raster", []byte{0x1d, 'v', '0', 0, 0xff, 0xff, 0xff, 0xff}, 0, 0},
+		{"wide raster", []byte{0x1d, 'v', '0', 0, 0x01, 0x02, 0x01, 0x00, 0xff}, 0, 0},
+		{"tall raster", []byte{0x1d, 'v', '0', 0, 0x01, 0x00, 0xff, 0xff, 0xff}, 0, 0},
+		{"truncated raster", []byte{0x1d, 'v', '0', 0, 0x02, 0x00, 0x00, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff}, 16, 2},
+		{"raster without data", []byte{0x1d, 'v', '0', 0, 0x10, 0x00, 0x10, 0x00}, 128, 0},
+		{"huge bit image", []byte{0x1b, '*', 33, 0xff, 0xff, 0xff}, 0, 0},
+		{"truncated bit image", []byte{0x1b, '*', 33, 0x00, 0x02, 0xff, 0xff, 0xff, 0xff}, 1, 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := Parse(tt.data, 80)
+			if len(doc.Elements) != 1 {
+				t.Fatalf("got %d elements, want 1: %#v", len(doc.Elements), doc.Elements)
+			}
+			switch e := doc.Elements[0].(type) {
+			case Marker:
+				if tt.wantWidth != 0 {
+					t.Fatalf("got marker %q, want a %dx%d image", e.Text, tt.wantWidth, tt.wantHeight)
+				}
+			case Image:
+				if e.Width != tt.wantWidth || e.Height != tt.wantHeight || len(e.Pix) != e.Width*e.Height {
+					t.Fatalf("image %dx%d with %d pixels, want %dx%d", e.Width, e.Height, len(e.Pix), tt.wantWidth, tt.wantHeight)
+				}
+			default:
+				t.Fatalf("got %T", e)
+			}
+
+			// Every format must render what was parsed
+			doc.Text()
+			doc.HTML()
+			if err := doc.PNG(&bytes.Buffer{}); err != nil {
+				t.Errorf("PNG: %v", err)
+			}
+		})
+	}
+}
+
+func TestPNGTooLong(t *testing.T) {
+	doc := Parse(bytes.Repeat([]byte{'\n'}, 5000), 80)
+	if err := doc.PNG(&bytes.Buffer{}); err == nil {
+		t.Error("PNG of 5000 lines succeeded, want an error")
+	}
+	if !strings.HasPrefix(doc.Text(), "\n\n") {
+		t.Error("text preview of blank lines is missing")
+	}
+}
+
+func TestParseTruncatedCommands(t *testing.T) {
+	// None of these may panic
+	for _, data := range [][]byte{
+		{0x1b}, {0x1d}, {0x1c}, {0x10}, {0x1b, '*'}, {0x1b, '*', 33}, {0x1d, 'v'}, {0x1d, 'v', '0'},
+		{0x1d, 'k'}, {0x1d, 'k', 73}, {0x1d, '(', 'k', 0xff, 0xff}, {0x1b, 'B', 1},
+	} {
+		Parse(data, 58)
+	}
+}
+
+func onlyImage(t *testing.T, doc *Document) Image {
+	t.Helper()
+	if len(doc.Elements) != 1 {
+		t.Fatalf("got %d elements, want 1", len(doc.Elements))
+	}
+	img, ok := doc.Elements[0].(Image)
+	if !ok {
+		t.Fatalf("got %T, want Image", doc.Elements[0])
+	}
+	return img
+}
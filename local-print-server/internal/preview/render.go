@@ -0,0 +1,423 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// Text renders the document as plain text, one character per Font A column. Wide
+// characters are followed by spaces to keep their printed width.
+func (d *Document) Text() string {
+	var b strings.Builder
+	for _, e := range d.Elements {
+		switch e := e.(type) {
+		case Line:
+			var line strings.Builder
+			for _, s := range e.Spans {
+				for _, r := range s.Text {
+					line.WriteRune(r)
+					line.WriteString(strings.Repeat(" ", s.Style.Width-1))
+				}
+			}
+			if text := strings.TrimRight(line.String(), " "); text != "" {
+				b.WriteString(d.pad(e.Align, e.Width()) + text)
+			}
+		case Image:
+			label := fmt.Sprintf("[image %dx%d]", e.Width, e.Height)
+			b.WriteString(d.pad(e.Align, len(label)) + label)
+		case Barcode:
+			label := fmt.Sprintf("[%s: %s]", e.Symbology, e.Data)
+			b.WriteString(d.pad(e.Align, len([]rune(label))) + label)
+		case Cut:
+			b.WriteString(cutLabel(e, d.Columns))
+		case Marker:
+			b.WriteString("[" + e.Text + "]")
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// pad returns the leading spaces that align width columns on the paper
+func (d *Document) pad(align Align, width int) string {
+	n := 0
+	switch align {
+	case AlignCenter:
+		n = (d.Columns - width) / 2
+	case AlignRight:
+		n = d.Columns - width
+	}
+	return strings.Repeat(" ", max(n, 0))
+}
+
+func (c Cut) name() string {
+	if c.Partial {
+		return "partial cut"
+	}
+	return "cut"
+}
+
+// cutLabel returns a dashed cut line the width of the paper
+func cutLabel(c Cut, columns int) string {
+	label := " " + c.name() + " "
+	dashes := max(columns-len(label), 2)
+	return strings.Repeat("-", dashes/2) + label + strings.Repeat("-", dashes-dashes/2)
+}
+
+// HTML renders the document as a standalone page showing the paper at its width
+func (d *Document) HTML() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Job preview</title>
+<style>
+body { background: #e5e7eb; margin: 0; padding: 24px; }
+.paper { background: #fff; margin: 0 auto; padding: 16px 12px; width: %dch; box-shadow: 0 1px 4px rgba(0,0,0,.2);
+  font-family: "DejaVu Sans Mono", Menlo, Consolas, monospace; font-size: 14px; line-height: 1.3; white-space: pre; }
+.line { min-height: 1.3em; }
+.wide { display: inline-block; transform-origin: 0 100%%; }
+.inv { background: #000; color: #fff; }
+.code { display: inline-block; border: 1px dashed #6b7280; padding: 6px 10px; margin: 4px 0; white-space: pre-wrap; word-break: break-all; }
+.cut { color: #9ca3af; margin: 8px 0; }
+.marker { color: #6b7280; font-style: italic; }
+img { image-rendering: pixelated; }
+</style>
+</head>
+<body>
+<div class="paper">
+`, d.Columns)
+
+	for _, e := range d.Elements {
+		switch e := e.(type) {
+		case Line:
+			height := 1
+			for _, s := range e.Spans {
+				height = max(height, s.Style.Height)
+			}
+			fmt.Fprintf(&b, `<div class="line" style="text-align:%s;height:%.1fem">`, alignCSS(e.Align), 1.3*float64(height))
+			for _, s := range e.Spans {
+				b.WriteString(spanHTML(s))
+			}
+			b.WriteString("</div>\n")
+		case Image:
+			uri, err := imageDataURI(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(&b, `<div style="text-align:%s"><img src="%s" style="width:%.2f%%" alt="image %dx%d"></div>`+"\n",
+				alignCSS(e.Align), uri, 100*float64(e.Width)/float64(d.Dots), e.Width, e.Height)
+		case Barcode:
+			fmt.Fprintf(&b, `<div style="text-align:%s"><span class="code">%s<br>%s</span></div>`+"\n",
+				alignCSS(e.Align), html.EscapeString(e.Symbology), html.EscapeString(e.Data))
+		case Cut:
+			fmt.Fprintf(&b, `<div class="cut">%s</div>`+"\n", cutLabel(e, d.Columns))
+		case Marker:
+			fmt.Fprintf(&b, `<div class="marker">[%s]</div>`+"\n", html.EscapeString(e.Text))
+		}
+	}
+	b.WriteString("</div>\n</body>\n</html>\n")
+	return b.String()
+}
+
+// spanHTML renders a span. Enlarged text is scaled from the bottom left and given the
+// extra width it takes on paper.
+func spanHTML(s Span) string {
+	var style []string
+	if s.Style.Bold {
+		style = append(style, "font-weight:bold")
+	}
+	if s.Style.Underline {
+		style = append(style, "text-decoration:underline")
+	}
+	class := ""
+	if s.Style.Invert {
+		class = "inv"
+	}
+	if s.Style.Width > 1 || s.Style.Height > 1 {
+		class = strings.TrimSpace(class + " wide")
+		n := len([]rune(s.Text))
+		style = append(style,
+			fmt.Sprintf("transform:scale(%d,%d)", s.Style.Width, s.Style.Height),
+			fmt.Sprintf("margin-right:%dch", n*(s.Style.Width-1)))
+	}
+
+	attrs := ""
+	if class != "" {
+		attrs += fmt.Sprintf(` class="%s"`, class)
+	}
+	if len(style) > 0 {
+		attrs += fmt.Sprintf(` style="%s"`, strings.Join(style, ";"))
+	}
+	return "<span" + attrs + ">" + html.EscapeString(s.Text) + "</span>"
+}
+
+func alignCSS(a Align) string {
+	switch a {
+	case AlignCenter:
+		return "center"
+	case AlignRight:
+		return "right"
+	}
+	return "left"
+}
+
+// imageDataURI encodes an image as a PNG data URI
+func imageDataURI(img Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img.bitmap()); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// bitmap converts an Image to a black and white image.Image
+func (img Image) bitmap() image.Image {
+	out := image.NewPaletted(image.Rect(0, 0, max(img.Width, 1), max(img.Height, 1)), pngPalette)
+	for y := 0; y < img.Height; y++ {
+		for x := 0; x < img.Width; x++ {
+			if img.Pix[y*img.Width+x] {
+				out.SetColorIndex(x, y, black)
+			}
+		}
+	}
+	return out
+}
+
+// PNG palette indexes
+const (
+	white uint8 = iota
+	black
+	grey
+)
+
+var pngPalette = color.Palette{color.White, color.Black, color.Gray{Y: 0x99}}
+
+// Layout of the PNG rendering, in printer dots
+const (
+	pngMargin     = 16  // Above and below the printed content
+	lineGap       = 6   // Added to the character height to make the default line spacing
+	barcodeHeight = 80  // 1D barcode bar height
+	symbolSize    = 160 // 2D symbol side
+	moduleWidth   = 2   // Narrowest bar or symbol module
+
+	maxPNGHeight = 40000 // About 5 m of paper
+)
+
+// PNG renders the document at the printer's resolution, one pixel per dot. Barcodes
+// and 2D symbols are drawn as look-alike patterns labelled with their content; they
+// show position and size, not a scannable code.
+func (d *Document) PNG(w io.Writer) error {
+	height := 2 * pngMargin
+	for _, e := range d.Elements {
+		height += d.elementHeight(e)
+	}
+	if height > maxPNGHeight {
+		return fmt.Errorf("preview is %d dots long, more than the %d a PNG can show; use format=txt", height, maxPNGHeight)
+	}
+
+	c := &canvas{img: image.NewPaletted(image.Rect(0, 0, d.Dots, height), pngPalette)}
+	y := pngMargin
+	for _, e := range d.Elements {
+		d.draw(c, e, y)
+		y += d.elementHeight(e)
+	}
+	return png.Encode(w, c.img)
+}
+
+// elementHeight returns the dots an element takes down the paper
+func (d *Document) elementHeight(e Element) int {
+	switch e := e.(type) {
+	case Line:
+		return lineHeight(e)
+	case Image:
+		return e.Height
+	case Barcode:
+		if is2D(e) {
+			return symbolSize + cellH + lineGap
+		}
+		return barcodeHeight + cellH + lineGap
+	}
+	return cellH + lineGap
+}
+
+func lineHeight(l Line) int {
+	h := 1
+	for _, s := range l.Spans {
+		h = max(h, s.Style.Height)
+	}
+	return h*cellH + lineGap
+}
+
+func is2D(b Barcode) bool {
+	return b.Symbology == "QR" || b.Symbology == "PDF417"
+}
+
+// alignX returns the left edge of something width dots wide
+func (d *Document) alignX(align Align, width int) int {
+	switch align {
+	case AlignCenter:
+		return max((d.Dots-width)/2, 0)
+	case AlignRight:
+		return max(d.Dots-width, 0)
+	}
+	return 0
+}
+
+func (d *Document) draw(c *canvas, e Element, y int) {
+	switch e := e.(type) {
+	case Line:
+		base := y + lineHeight(e) - lineGap // Characters of all heights share a baseline
+		x := d.alignX(e.Align, e.Width()*cellW)
+		for _, s := range e.Spans {
+			for _, r := range s.Text {
+				c.char(x, base-s.Style.Height*cellH, r, s.Style, black)
+				x += s.Style.Width * cellW
+			}
+		}
+	case Image:
+		x := d.alignX(e.Align, e.Width)
+		for iy := 0; iy < e.Height; iy++ {
+			for ix := 0; ix < e.Width; ix++ {
+				if e.Pix[iy*e.Width+ix] {
+					c.set(x+ix, y+iy, black)
+				}
+			}
+		}
+	case Barcode:
+		d.drawBarcode(c, e, y)
+	case Cut:
+		// Dashed line across the paper, broken for the label
+		label := " " + e.name() + " "
+		lx := d.alignX(AlignCenter, len(label)*cellW)
+		for x := 0; x < d.Dots; x++ {
+			if x/8%2 == 0 && (x < lx || x >= lx+len(label)*cellW) {
+				c.set(x, y+cellH/2, grey)
+			}
+		}
+		c.text(lx, y, label, grey)
+	case Marker:
+		label := "[" + e.Text + "]"
+		c.text(0, y, label, grey)
+	}
+}
+
+// drawBarcode draws a pattern derived from the data, with the symbology and data below
+func (d *Document) drawBarcode(c *canvas, b Barcode, y int) {
+	label := b.Symbology + ": " + b.Data
+	if n := d.Columns; len([]rune(label)) > n {
+		label = string([]rune(label)[:n-3]) + "..."
+	}
+	bits := dataBits(b.Data)
+
+	if is2D(b) {
+		x := d.alignX(b.Align, symbolSize)
+		modules := symbolSize / (2 * moduleWidth)
+		for my := 0; my < modules; my++ {
+			for mx := 0; mx < modules; mx++ {
+				if bits[(my*modules+mx)%len(bits)] {
+					c.fill(x+mx*2*moduleWidth, y+my*2*moduleWidth, 2*moduleWidth, 2*moduleWidth, black)
+				}
+			}
+		}
+		// Finder patterns in three corners
+		finder := 7 * 2 * moduleWidth
+		for _, corner := range [][2]int{{0, 0}, {symbolSize - finder, 0}, {0, symbolSize - finder}} {
+			fx, fy := x+corner[0], y+corner[1]
+			c.fill(fx, fy, finder, finder, black)
+			c.fill(fx+2*moduleWidth, fy+2*moduleWidth, finder-4*moduleWidth, finder-4*moduleWidth, white)
+			c.fill(fx+4*moduleWidth, fy+4*moduleWidth, finder-8*moduleWidth, finder-8*moduleWidth, black)
+		}
+		y += symbolSize
+	} else {
+		// Start and stop guards around the data bits
+		pattern := append(append([]bool{true, false, true, true}, bits...), true, true, false, true)
+		width := min(len(pattern)*moduleWidth, d.Dots)
+		x := d.alignX(b.Align, width)
+		for i, bar := range pattern {
+			if bar && (i+1)*moduleWidth <= width {
+				c.fill(x+i*moduleWidth, y, moduleWidth, barcodeHeight, black)
+			}
+		}
+		y += barcodeHeight
+	}
+	c.text(d.alignX(b.Align, len([]rune(label))*cellW), y+lineGap/2, label, black)
+}
+
+// dataBits spreads data over a bit pattern for look-alike barcodes
+func dataBits(data string) []bool {
+	if data == "" {
+		data = "?"
+	}
+	bits := make([]bool, 0, len(data)*8)
+	for i := 0; i < len(data); i++ {
+		b := data[i] ^ byte(i*37)
+		for bit := 7; bit >= 0; bit-- {
+			bits = append(bits, b>>bit&1 == 1)
+		}
+	}
+	return bits
+}
+
+// canvas draws into a paletted image, ignoring anything off the edge
+type canvas struct {
+	img *image.Paletted
+}
+
+func (c *canvas) set(x, y int, idx uint8) {
+	if image.Pt(x, y).In(c.img.Rect) {
+		c.img.SetColorIndex(x, y, idx)
+	}
+}
+
+func (c *canvas) fill(x, y, w, h int, idx uint8) {
+	for dy := 0; dy < h; dy++ {
+		for dx := 0; dx < w; dx++ {
+			c.set(x+dx, y+dy, idx)
+		}
+	}
+}
+
+// text draws a run of normal-sized characters
+func (c *canvas) text(x, y int, s string, idx uint8) {
+	for _, r := range s {
+		c.char(x, y, r, Style{Width: 1, Height: 1}, idx)
+		x += cellW
+	}
+}
+
+// char draws one character cell with its top left at x, y
+func (c *canvas) char(x, y int, r rune, st Style, idx uint8) {
+	w, h := st.Width*cellW, st.Height*cellH
+	fg := idx
+	if st.Invert {
+		c.fill(x, y, w, h, black)
+		fg = white
+	}
+
+	px, py := glyphSX*st.Width, glyphSY*st.Height
+	ox, oy := x+st.Width, y+st.Height
+	g := glyph(r)
+	for col := 0; col < glyphCols; col++ {
+		for row := 0; row < glyphRows; row++ {
+			if g[col]>>row&1 == 0 {
+				continue
+			}
+			c.fill(ox+col*px, oy+row*py, px, py, fg)
+			if st.Bold {
+				c.fill(ox+col*px+st.Width, oy+row*py, px, py, fg)
+			}
+		}
+	}
+	if st.Underline {
+		c.fill(x, y+h-2*st.Height, w, 2*st.Height, fg)
+	}
+}
@@ -0,0 +1,184 @@
+package printer
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// PrinterHealth is the cached result of the last health probe
+type PrinterHealth struct {
+	Status    string    `json:"status"`
+	Reasons   []string  `json:"reasons,omitempty"` // Every condition detected, e.g. paper_out and cover_open
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Prober is implemented by printers that can report detailed status conditions
+type Prober interface {
+	Probe() (status string, reasons []string)
+}
+
+// healthMonitor probes each printer on its own jittered schedule and caches the results,
+// so readers never wait on a TCP dial
+type healthMonitor struct {
+	mu       sync.RWMutex
+	running  bool
+	interval time.Duration
+	results  map[string]PrinterHealth
+	stops    map[string]chan struct{}
+}
+
+func newHealthMonitor() *healthMonitor {
+	return &healthMonitor{
+		results: make(map[string]PrinterHealth),
+		stops:   make(map[string]chan struct{}),
+	}
+}
+
+// StartHealthMonitor begins probing every printer roughly once per interval
+func (m *Manager) StartHealthMonitor(interval time.Duration) {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	m.health.mu.Lock()
+	m.health.running = true
+	m.health.interval = interval
+	m.health.mu.Unlock()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, p := range m.printers {
+		m.watch(p)
+	}
+}
+
+// StopHealthMonitor stops all probes
+func (m *Manager) StopHealthMonitor() {
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+
+	m.health.running = false
+	for id, stop := range m.health.stops {
+		close(stop)
+		delete(m.health.stops, id)
+	}
+}
+
+// Health returns the cached health of a printer without probing it.
+// Printers that have not been probed yet report "unknown".
+func (m *Manager) Health(id string) PrinterHealth {
+	m.health.mu.RLock()
+	defer m.health.mu.RUnlock()
+
+	if h, ok := m.health.results[id]; ok {
+		return h
+	}
+	return PrinterHealth{Status: StatusUnknown}
+}
+
+// CheckHealth probes a printer immediately and updates the cache
+func (m *Manager) CheckHealth(id string) PrinterHealth {
+	p, err := m.GetPrinter(id)
+	if err != nil {
+		return PrinterHealth{Status: StatusUnknown}
+	}
+	return m.probe(p)
+}
+
+// watch starts the probe loop for a printer, replacing any existing one
+func (m *Manager) watch(p Printer) {
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+
+	if !m.health.running {
+		return
+	}
+	if stop, ok := m.health.stops[p.ID()]; ok {
+		close(stop)
+	}
+	stop := make(chan struct{})
+	m.health.stops[p.ID()] = stop
+	go m.probeLoop(p, m.health.interval, stop)
+}
+
+// unwatch stops the probe loop for a printer and drops its cached health.
+// Status history is kept, since printers are re-created when their settings change.
+func (m *Manager) unwatch(id string) {
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+
+	if stop, ok := m.health.stops[id]; ok {
+		close(stop)
+		delete(m.health.stops, id)
+	}
+	delete(m.health.results, id)
+}
+
+func (m *Manager) probeLoop(p Printer, interval time.Duration, stop chan struct{}) {
+	// Spread the first probes so printers added together don't dial at once
+	delay := time.Duration(rand.Int64N(int64(time.Second)))
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+
+		m.probe(p)
+		delay = jitter(interval)
+	}
+}
+
+// busyPrinter is implemented by printers that can tell a print is in progress
+type busyPrinter interface {
+	Busy() bool
+}
+
+// probe checks a printer's status and caches the result. A printer in the middle of a
+// print is answering, so it keeps its last result rather than waiting for the job.
+func (m *Manager) probe(p Printer) PrinterHealth {
+	if bp, ok := p.(busyPrinter); ok && bp.Busy() {
+		m.health.mu.Lock()
+		h, ok := m.health.results[p.ID()]
+		m.health.mu.Unlock()
+		if ok {
+			return h
+		}
+	}
+
+	var h PrinterHealth
+	if pr, ok := p.(Prober); ok {
+		h.Status, h.Reasons = pr.Probe()
+	} else {
+		h.Status = p.Status()
+		if !IsUp(h.Status) {
+			h.Reasons = []string{h.Status}
+		}
+	}
+	h.CheckedAt = time.Now()
+
+	m.health.mu.Lock()
+	// Skip the write if the printer was removed while probing
+	changed := false
+	if _, ok := m.health.stops[p.ID()]; ok || !m.health.running {
+		m.health.results[p.ID()] = h
+		changed = m.history.record(p.ID(), h.Status, h.CheckedAt)
+	}
+	m.health.mu.Unlock()
+
+	if changed && m.OnStatusChange != nil {
+		m.OnStatusChange(p.ID(), h)
+	}
+	return h
+}
+
+// jitter returns d randomly adjusted by up to ±20%
+func jitter(d time.Duration) time.Duration {
+	spread := int64(d) * 2 / 5
+	if spread <= 0 {
+		return d
+	}
+	return d - time.Duration(spread/2) + time.Duration(rand.Int64N(spread))
+}
@@ -0,0 +1,154 @@
+package printer
+
+import (
+	"sync"
+	"time"
+)
+
+// History bounds, per printer. Entries older than the retention are dropped,
+// except the last one before the cutoff, which gives the state at the start of the 7d window.
+const (
+	historyMaxEntries = 1000
+	historyRetention  = 7 * 24 * time.Hour
+)
+
+// StatusChange records a printer status transition
+type StatusChange struct {
+	From string    `json:"from,omitempty"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// UptimeStats summarizes printer availability over a window
+type UptimeStats struct {
+	Window        string  `json:"window"`
+	UptimePercent float64 `json:"uptime_percent"`
+	ObservedSecs  float64 `json:"observed_seconds"`
+	Outages       int     `json:"outages"`
+	MTTRSecs      float64 `json:"mttr_seconds,omitempty"` // Mean time to recovery over outages that ended in the window
+}
+
+// statusHistory is a bounded, thread-safe store of status transitions per printer
+type statusHistory struct {
+	mu      sync.RWMutex
+	entries map[string][]StatusChange
+}
+
+func newStatusHistory() *statusHistory {
+	return &statusHistory{entries: make(map[string][]StatusChange)}
+}
+
+// record appends a transition if status differs from the last recorded one.
+// Returns true if a transition was recorded.
+func (h *statusHistory) record(printerID, status string, at time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	list := h.entries[printerID]
+	from := ""
+	if len(list) > 0 {
+		from = list[len(list)-1].To
+		if from == status {
+			return false
+		}
+	}
+	list = append(list, StatusChange{From: from, To: status, At: at})
+
+	// Keep the newest entry before the cutoff; drop anything older
+	cutoff := at.Add(-historyRetention)
+	drop := 0
+	for drop+1 < len(list) && list[drop+1].At.Before(cutoff) {
+		drop++
+	}
+	if n := len(list) - drop; n > historyMaxEntries {
+		drop += n - historyMaxEntries
+	}
+	h.entries[printerID] = append([]StatusChange(nil), list[drop:]...)
+	return true
+}
+
+// list returns a printer's transitions, newest first
+func (h *statusHistory) list(printerID string) []StatusChange {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	list := h.entries[printerID]
+	result := make([]StatusChange, len(list))
+	for i, j := 0, len(list)-1; j >= 0; i, j = i+1, j-1 {
+		result[i] = list[j]
+	}
+	return result
+}
+
+// stats computes uptime over the window ending now.
+// Time before the first recorded status (e.g. before startup) is not counted.
+func (h *statusHistory) stats(printerID, label string, window time.Duration, now time.Time) UptimeStats {
+	h.mu.RLock()
+	list := append([]StatusChange(nil), h.entries[printerID]...)
+	h.mu.RUnlock()
+
+	start := now.Add(-window)
+	st := UptimeStats{Window: label}
+
+	var upTime, observed time.Duration
+	var recoveries int
+	var recoveryTime time.Duration
+	var downSince time.Time
+
+	for i, c := range list {
+		end := now
+		if i+1 < len(list) {
+			end = list[i+1].At
+		}
+		if end.Before(start) {
+			continue
+		}
+
+		begin := c.At
+		if begin.Before(start) {
+			begin = start
+		}
+		if c.To != StatusUnknown {
+			observed += end.Sub(begin)
+			if IsUp(c.To) {
+				upTime += end.Sub(begin)
+			}
+		}
+
+		// Outages: transitions from up to down inside the window
+		wasUp := c.From == "" || IsUp(c.From)
+		if !IsUp(c.To) && c.To != StatusUnknown {
+			if wasUp && !c.At.Before(start) {
+				st.Outages++
+			}
+			if downSince.IsZero() {
+				downSince = c.At
+			}
+		} else if IsUp(c.To) && !downSince.IsZero() {
+			if !c.At.Before(start) {
+				recoveries++
+				recoveryTime += c.At.Sub(downSince)
+			}
+			downSince = time.Time{}
+		}
+	}
+
+	st.ObservedSecs = observed.Seconds()
+	if observed > 0 {
+		st.UptimePercent = float64(upTime) / float64(observed) * 100
+	}
+	if recoveries > 0 {
+		st.MTTRSecs = (recoveryTime / time.Duration(recoveries)).Seconds()
+	}
+	return st
+}
+
+// StatusHistory returns a printer's recorded status transitions, newest first
+func (m *Manager) StatusHistory(printerID string) []StatusChange {
+	return m.history.list(printerID)
+}
+
+// Uptime returns availability statistics for a printer over the given window
+func (m *Manager) Uptime(printerID, label string, window time.Duration) UptimeStats {
+	return m.history.stats(printerID, label, window, time.Now())
+}
@@ -0,0 +1,163 @@
+package printer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GS I parameters (transmit printer ID)
+const (
+	gsIModelID      = 0x01
+	gsITypeID       = 0x02
+	gsIFirmware     = 0x41
+	gsIManufacturer = 0x42
+	gsIModelName    = 0x43
+	gsISerial       = 0x44
+)
+
+// Type ID bits returned by GS I 2
+const (
+	typeIDMultiByte = 0x01
+	typeIDCutter    = 0x02
+)
+
+// PrinterInfo holds the identification details reported by a printer via GS I
+type PrinterInfo struct {
+	ModelID      int       `json:"model_id"`
+	TypeID       int       `json:"type_id"`
+	Firmware     string    `json:"firmware,omitempty"`
+	Manufacturer string    `json:"manufacturer,omitempty"`
+	ModelName    string    `json:"model_name,omitempty"`
+	Serial       string    `json:"serial,omitempty"`
+	HasCutter    bool      `json:"has_cutter"`
+	MultiByte    bool      `json:"multi_byte"`
+	QueriedAt    time.Time `json:"queried_at"`
+
+	// Suggestions derived from the model name
+	SuggestedPaperWidth int    `json:"suggested_paper_width,omitempty"`
+	SuggestedProfile    string `json:"suggested_profile,omitempty"`
+}
+
+// Identifier is implemented by printers that can report their identity
+type Identifier interface {
+	// Identify queries the printer and caches the result
+	Identify() (*PrinterInfo, error)
+	// Info returns the cached identification, or nil if never queried
+	Info() *PrinterInfo
+}
+
+// QueryInfo connects to a network printer and queries it with GS I
+func QueryInfo(address string, port int, timeout time.Duration) (*PrinterInfo, error) {
+	addr := net.JoinHostPort(address, strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to printer: %w", err)
+	}
+	defer conn.Close()
+
+	return queryInfo(conn, timeout)
+}
+
+// queryInfo runs the GS I queries over an open connection
+func queryInfo(conn net.Conn, timeout time.Duration) (*PrinterInfo, error) {
+	r := bufio.NewReader(conn)
+	info := &PrinterInfo{QueriedAt: time.Now()}
+	answered := false
+
+	if b, err := queryByte(conn, r, gsIModelID, timeout); err == nil {
+		info.ModelID = int(b)
+		answered = true
+	}
+	if b, err := queryByte(conn, r, gsITypeID, timeout); err == nil {
+		info.TypeID = int(b)
+		info.MultiByte = b&typeIDMultiByte != 0
+		info.HasCutter = b&typeIDCutter != 0
+		answered = true
+	}
+
+	fields := []struct {
+		n   byte
+		dst *string
+	}{
+		{gsIFirmware, &info.Firmware},
+		{gsIManufacturer, &info.Manufacturer},
+		{gsIModelName, &info.ModelName},
+		{gsISerial, &info.Serial},
+	}
+	for _, f := range fields {
+		if s, err := queryString(conn, r, f.n, timeout); err == nil {
+			*f.dst = s
+			answered = true
+		}
+	}
+
+	if !answered {
+		return nil, errors.New("printer did not answer GS I queries")
+	}
+
+	info.SuggestedPaperWidth, info.SuggestedProfile = suggestSettings(info.Manufacturer, info.ModelName)
+	return info, nil
+}
+
+// queryByte sends GS I n and reads a single-byte response
+func queryByte(conn net.Conn, r *bufio.Reader, n byte, timeout time.Duration) (byte, error) {
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write([]byte{0x1D, 0x49, n}); err != nil {
+		return 0, err
+	}
+	return r.ReadByte()
+}
+
+// queryString sends GS I n and reads a "_" ... NUL framed response
+func queryString(conn net.Conn, r *bufio.Reader, n byte, timeout time.Duration) (string, error) {
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write([]byte{0x1D, 0x49, n}); err != nil {
+		return "", err
+	}
+
+	// Skip anything before the header (e.g. automatic status bytes)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b == 0x5F {
+			break
+		}
+	}
+
+	s, err := r.ReadString(0x00)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimSuffix(s, "\x00")), nil
+}
+
+// suggestSettings guesses paper width and profile from the reported model
+func suggestSettings(manufacturer, model string) (int, string) {
+	m := strings.ToUpper(manufacturer + " " + model)
+
+	profile := "generic"
+	switch {
+	case strings.Contains(m, "EPSON"), strings.Contains(m, "TM-"),
+		strings.Contains(m, "STAR"), strings.Contains(m, "TSP"),
+		strings.Contains(m, "BIXOLON"), strings.Contains(m, "SRP-"),
+		strings.Contains(m, "CITIZEN"), strings.Contains(m, "CT-S"):
+		profile = "epson" // All follow the Epson command set in ESC/POS mode
+	}
+
+	width := 0
+	switch {
+	case strings.Contains(m, "58"), strings.Contains(m, "TM-P20"):
+		width = 58
+	case model != "":
+		width = 80
+	}
+
+	return width, profile
+}
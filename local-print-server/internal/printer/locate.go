@@ -0,0 +1,249 @@
+package printer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How a network printer is pinned to its identity
+const (
+	PinByAddress    = "address"     // Fixed IP address (default)
+	PinByMAC        = "mac"         // Hardware address from the ARP table
+	PinBySerial     = "serial"      // Serial number reported by GS I
+	PinBySNMPSerial = "snmp_serial" // prtGeneralSerialNumber via SNMP
+)
+
+// ValidPinBy reports whether by is a known way of pinning a printer; "" means PinByAddress
+func ValidPinBy(by string) bool {
+	switch by {
+	case "", PinByAddress, PinByMAC, PinBySerial, PinBySNMPSerial:
+		return true
+	}
+	return false
+}
+
+// Pin identifies a network printer independently of its IP address
+type Pin struct {
+	By         string
+	MAC        string
+	Serial     string
+	SNMPSerial string
+}
+
+// Value returns the identifier the pin matches on, or "" if not yet known
+func (p Pin) Value() string {
+	switch p.By {
+	case PinByMAC:
+		return p.MAC
+	case PinBySerial:
+		return p.Serial
+	case PinBySNMPSerial:
+		return p.SNMPSerial
+	}
+	return ""
+}
+
+// Pinned reports whether the printer should be followed across address changes
+func (p Pin) Pinned() bool {
+	return p.By != "" && p.By != PinByAddress
+}
+
+// ErrNotFound is returned when a pinned printer cannot be found on the network
+var ErrNotFound = errors.New("printer not found on local network")
+
+// Locate scans the given subnets for the printer matching pin and returns its address.
+// Subnets are "a.b.c." prefixes, as used by discovery.
+func Locate(pin Pin, port int, subnets []string) (string, error) {
+	want := pin.Value()
+	if want == "" {
+		return "", fmt.Errorf("no %s recorded for printer", pin.By)
+	}
+
+	// A MAC pin can often be resolved from the ARP table without scanning
+	if pin.By == PinByMAC {
+		if ip := arpLookupIP(want); ip != "" && isPortOpen(ip, port, 500*time.Millisecond) {
+			return ip, nil
+		}
+	}
+
+	for _, ip := range sweep(subnets, port, 200*time.Millisecond) {
+		if identify(pin.By, ip, port) == want {
+			return ip, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// identify reads the identifier of the given kind from the printer at ip
+func identify(by, ip string, port int) string {
+	switch by {
+	case PinByMAC:
+		return arpLookupMAC(ip)
+	case PinBySerial:
+		if info, err := QueryInfo(ip, port, time.Second); err == nil {
+			return info.Serial
+		}
+	case PinBySNMPSerial:
+		if serial, err := snmpGetString(ip, "public", oidPrtSerialNumber, time.Second); err == nil {
+			return serial
+		}
+	}
+	return ""
+}
+
+// sweep returns the addresses in subnets that accept connections on port
+func sweep(subnets []string, port int, timeout time.Duration) []string {
+	var (
+		mu    sync.Mutex
+		found []string
+		wg    sync.WaitGroup
+	)
+	sem := make(chan struct{}, 32)
+
+	for _, subnet := range subnets {
+		for i := 1; i <= 254; i++ {
+			ip := fmt.Sprintf("%s%d", subnet, i)
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if isPortOpen(ip, port, timeout) {
+					mu.Lock()
+					found = append(found, ip)
+					mu.Unlock()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	return found
+}
+
+// LocalSubnets returns the /24 prefixes of this host's IPv4 interfaces,
+// with the subnet of hint (if any) first
+func LocalSubnets(hint string) []string {
+	seen := make(map[string]bool)
+	var subnets []string
+	add := func(ip net.IP) {
+		ip4 := ip.To4()
+		if ip4 == nil || ip4.IsLoopback() {
+			return
+		}
+		prefix := fmt.Sprintf("%d.%d.%d.", ip4[0], ip4[1], ip4[2])
+		if !seen[prefix] {
+			seen[prefix] = true
+			subnets = append(subnets, prefix)
+		}
+	}
+
+	if ip := net.ParseIP(hint); ip != nil {
+		add(ip)
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return subnets
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok {
+			add(ipnet.IP)
+		}
+	}
+	return subnets
+}
+
+// arpEntry matches an IPv4 address followed by a MAC on a line of `arp -a` output
+// (BSD, macOS, net-tools and Windows formats)
+var arpEntry = regexp.MustCompile(`(\d+\.\d+\.\d+\.\d+)\D+?([0-9a-fA-F]{1,2}(?:[:-][0-9a-fA-F]{1,2}){5})`)
+
+// arpTable returns the host's ARP cache as IP -> normalized MAC
+func arpTable() map[string]string {
+	if data, err := os.ReadFile("/proc/net/arp"); err == nil {
+		return parseProcARP(string(data))
+	}
+	args := []string{"-an"}
+	if runtime.GOOS == "windows" {
+		args = []string{"-a"}
+	}
+	out, _ := exec.Command("arp", args...).Output()
+	return parseARPOutput(string(out))
+}
+
+// parseProcARP reads Linux /proc/net/arp: a header line, then whitespace-separated
+// columns with the IP address first and the MAC fourth
+func parseProcARP(data string) map[string]string {
+	table := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		addARPEntry(table, fields[0], fields[3])
+	}
+	return table
+}
+
+// parseARPOutput reads the output of `arp -an` or Windows `arp -a`
+func parseARPOutput(out string) map[string]string {
+	table := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		if m := arpEntry.FindStringSubmatch(line); m != nil {
+			addARPEntry(table, m[1], m[2])
+		}
+	}
+	return table
+}
+
+// addARPEntry records a resolved entry; incomplete ones have no usable MAC
+func addARPEntry(table map[string]string, ip, mac string) {
+	if net.ParseIP(ip) == nil {
+		return
+	}
+	if mac = NormalizeMAC(mac); mac != "" && mac != "00:00:00:00:00:00" {
+		table[ip] = mac
+	}
+}
+
+// arpLookupMAC returns the MAC address for ip from the ARP cache
+func arpLookupMAC(ip string) string {
+	return arpTable()[ip]
+}
+
+// arpLookupIP returns the IP address for mac from the ARP cache
+func arpLookupIP(mac string) string {
+	mac = NormalizeMAC(mac)
+	for ip, m := range arpTable() {
+		if m == mac {
+			return ip
+		}
+	}
+	return ""
+}
+
+// NormalizeMAC formats a MAC address as lower-case, colon-separated, zero-padded octets.
+// Returns "" if s is not a MAC address.
+func NormalizeMAC(s string) string {
+	parts := strings.FieldsFunc(strings.TrimSpace(s), func(r rune) bool { return r == ':' || r == '-' })
+	if len(parts) != 6 {
+		return ""
+	}
+	for i, p := range parts {
+		if len(p) == 1 {
+			p = "0" + p
+		}
+		parts[i] = strings.ToLower(p)
+	}
+	if _, err := net.ParseMAC(strings.Join(parts, ":")); err != nil {
+		return ""
+	}
+	return strings.Join(parts, ":")
+}
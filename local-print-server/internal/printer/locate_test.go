@@ -0,0 +1,85 @@
+package printer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseARP(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func(string) map[string]string
+		data  string
+		want  map[string]string
+	}{
+		{
+			name:  "linux /proc/net/arp",
+			parse: parseProcARP,
+			data: `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.50     0x1         0x2         00:1b:a9:aa:bb:cc     *        eth0
+192.168.1.1      0x1         0x2         dc:a6:32:01:02:03     *        wlan0
+192.168.1.77     0x1         0x0         00:00:00:00:00:00     *        eth0
+`,
+			want: map[string]string{"192.168.1.50": "00:1b:a9:aa:bb:cc", "192.168.1.1": "dc:a6:32:01:02:03"},
+		},
+		{
+			name:  "linux arp -an",
+			parse: parseARPOutput,
+			data: `? (192.168.1.50) at 00:1b:a9:aa:bb:cc [ether] on eth0
+? (192.168.1.77) at <incomplete> on eth0
+? (192.168.1.1) at dc:a6:32:01:02:03 [ether] on eth0
+`,
+			want: map[string]string{"192.168.1.50": "00:1b:a9:aa:bb:cc", "192.168.1.1": "dc:a6:32:01:02:03"},
+		},
+		{
+			name:  "macos arp -an",
+			parse: parseARPOutput,
+			data: `? (192.168.1.1) at dc:a6:32:1:2:3 on en0 ifscope [ethernet]
+? (192.168.1.50) at 0:1b:a9:aa:bb:cc on en0 ifscope [ethernet]
+? (192.168.1.77) at (incomplete) on en0 ifscope [ethernet]
+? (224.0.0.251) at 1:0:5e:0:0:fb on en0 ifscope permanent [ethernet]
+`,
+			want: map[string]string{
+				"192.168.1.1":  "dc:a6:32:01:02:03",
+				"192.168.1.50": "00:1b:a9:aa:bb:cc",
+				"224.0.0.251":  "01:00:5e:00:00:fb",
+			},
+		},
+		{
+			name:  "windows arp -a",
+			parse: parseARPOutput,
+			data: "\r\nInterface: 192.168.1.10 --- 0x4\r\n" +
+				"  Internet Address      Physical Address      Type\r\n" +
+				"  192.168.1.1           dc-a6-32-01-02-03     dynamic\r\n" +
+				"  192.168.1.50          00-1b-a9-aa-bb-cc     dynamic\r\n" +
+				"  192.168.1.255         ff-ff-ff-ff-ff-ff     static\r\n",
+			want: map[string]string{
+				"192.168.1.1":   "dc:a6:32:01:02:03",
+				"192.168.1.50":  "00:1b:a9:aa:bb:cc",
+				"192.168.1.255": "ff:ff:ff:ff:ff:ff",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.parse(tt.data)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeMAC(t *testing.T) {
+	tests := map[string]string{
+		"00:1B:A9:AA:BB:CC": "00:1b:a9:aa:bb:cc",
+		"0-1b-a9-aa-bb-c":   "00:1b:a9:aa:bb:0c",
+		"00:1b:a9:aa:bb":    "",
+		"zz:1b:a9:aa:bb:cc": "",
+	}
+	for in, want := range tests {
+		if got := NormalizeMAC(in); got != want {
+			t.Errorf("NormalizeMAC(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
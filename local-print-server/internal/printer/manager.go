@@ -4,12 +4,22 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
+	"sync"
 	"time"
+
+	"github.com/jetsetgo/local-print-server/internal/escpos"
 )
 
 // Manager manages printer connections and print jobs
 type Manager struct {
 	printers map[string]Printer
+	mu       sync.RWMutex
+	health   *healthMonitor
+	history  *statusHistory
+
+	// OnStatusChange is called when a health probe finds a printer in a new status
+	OnStatusChange func(printerID string, health PrinterHealth)
 }
 
 // Printer represents a thermal printer
@@ -22,6 +32,63 @@ type Printer interface {
 	Close() error
 }
 
+// TracedPrinter is implemented by printers that report the progress of a print,
+// so connecting and sending can be timed separately
+type TracedPrinter interface {
+	PrintTraced(data []byte, trace *PrintTrace) error
+}
+
+// PrintTrace receives progress from a print. Nil hooks are skipped.
+type PrintTrace struct {
+	Connected func()                   // The connection is open; nothing written yet
+	Progress  func(written, total int) // Called after each chunk is written
+	Cancelled func() bool              // Checked between chunks; true stops the print with ErrCancelled
+}
+
+// cancelPoll is how often a throttled print checks for cancellation while it waits
+const cancelPoll = 100 * time.Millisecond
+
+func (t *PrintTrace) connected() {
+	if t != nil && t.Connected != nil {
+		t.Connected()
+	}
+}
+
+func (t *PrintTrace) progress(written, total int) {
+	if t != nil && t.Progress != nil {
+		t.Progress(written, total)
+	}
+}
+
+func (t *PrintTrace) cancelled() bool {
+	return t != nil && t.Cancelled != nil && t.Cancelled()
+}
+
+// wait sleeps for d, returning false early if the print is cancelled
+func (t *PrintTrace) wait(d time.Duration) bool {
+	for end := time.Now().Add(d); ; {
+		if t.cancelled() {
+			return false
+		}
+		left := time.Until(end)
+		if left <= 0 {
+			return true
+		}
+		time.Sleep(min(left, cancelPoll))
+	}
+}
+
+// ErrConnect is returned when a printer cannot be reached. Jobs failing with it
+// can be queued and retried once the printer is back.
+var ErrConnect = errors.New("failed to connect to printer")
+
+// ErrWrite is returned when the connection drops while data is being sent,
+// e.g. the printer was power-cycled mid-job.
+var ErrWrite = errors.New("failed to send data to printer")
+
+// ErrCancelled is returned when PrintTrace.Cancelled stops a print part way through
+var ErrCancelled = errors.New("print cancelled")
+
 // DiscoveredPrinter represents a discovered printer
 type DiscoveredPrinter struct {
 	ID       string `json:"id"`
@@ -30,22 +97,33 @@ type DiscoveredPrinter struct {
 	Address  string `json:"address,omitempty"`
 	Port     int    `json:"port,omitempty"`
 	VendorID string `json:"vendor_id,omitempty"`
+
+	// Info is the GS I identification, if the printer answered
+	Info *PrinterInfo `json:"info,omitempty"`
 }
 
 // NewManager creates a new printer manager
 func NewManager() *Manager {
 	return &Manager{
 		printers: make(map[string]Printer),
+		health:   newHealthMonitor(),
+		history:  newStatusHistory(),
 	}
 }
 
 // AddPrinter adds a printer to the manager
 func (m *Manager) AddPrinter(p Printer) {
+	m.mu.Lock()
 	m.printers[p.ID()] = p
+	m.mu.Unlock()
+
+	m.watch(p)
 }
 
 // GetPrinter gets a printer by ID
 func (m *Manager) GetPrinter(id string) (Printer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	p, ok := m.printers[id]
 	if !ok {
 		return nil, errors.New("printer not found: " + id)
@@ -55,17 +133,22 @@ func (m *Manager) GetPrinter(id string) (Printer, error) {
 
 // RemovePrinter removes a printer from the manager
 func (m *Manager) RemovePrinter(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	p, ok := m.printers[id]
 	if !ok {
 		return errors.New("printer not found: " + id)
 	}
 	p.Close()
 	delete(m.printers, id)
+	m.unwatch(id)
 	return nil
 }
 
 // ListPrinters returns all printer IDs
 func (m *Manager) ListPrinters() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	ids := make([]string, 0, len(m.printers))
 	for id := range m.printers {
 		ids = append(ids, id)
@@ -75,25 +158,59 @@ func (m *Manager) ListPrinters() []string {
 
 // Print sends data to a printer
 func (m *Manager) Print(printerID string, data []byte) error {
+	return m.PrintTraced(printerID, data, nil)
+}
+
+// PrintTraced sends data to a printer, reporting progress to trace (which may be nil).
+// Printers that are not a TracedPrinter report Connected just before printing and
+// Progress once at the end.
+func (m *Manager) PrintTraced(printerID string, data []byte, trace *PrintTrace) error {
 	p, err := m.GetPrinter(printerID)
 	if err != nil {
 		return err
 	}
-	return p.Print(data)
+	if tp, ok := p.(TracedPrinter); ok {
+		err = tp.PrintTraced(data, trace)
+	} else {
+		trace.connected()
+		if err = p.Print(data); err == nil {
+			trace.progress(len(data), len(data))
+		}
+	}
+	if err != nil {
+		// Refresh the cached status rather than waiting for the next scheduled probe
+		go m.probe(p)
+		return err
+	}
+	return nil
 }
 
-// TestPrint sends a test print to a printer
-func (m *Manager) TestPrint(printerID string) error {
+// TestPrint sends a test print to a printer, using the commands of its profile
+func (m *Manager) TestPrint(printerID string, profile escpos.Profile) error {
 	p, err := m.GetPrinter(printerID)
 	if err != nil {
 		return err
 	}
 
 	// ESC/POS test receipt
-	testData := buildTestReceipt()
+	testData := buildTestReceipt(profile)
 	return p.Print(testData)
 }
 
+// Identify queries a printer for its identification details
+func (m *Manager) Identify(printerID string) (*PrinterInfo, error) {
+	p, err := m.GetPrinter(printerID)
+	if err != nil {
+		return nil, err
+	}
+
+	idp, ok := p.(Identifier)
+	if !ok {
+		return nil, fmt.Errorf("printer %s does not support identification", printerID)
+	}
+	return idp.Identify()
+}
+
 // Discover scans for available printers
 func (m *Manager) Discover() ([]DiscoveredPrinter, error) {
 	discovered := make([]DiscoveredPrinter, 0)
@@ -120,13 +237,20 @@ func discoverNetworkPrinters() []DiscoveredPrinter {
 		for i := 1; i <= 254; i++ {
 			ip := fmt.Sprintf("%s%d", subnet, i)
 			if isPortOpen(ip, 9100, 100*time.Millisecond) {
-				discovered = append(discovered, DiscoveredPrinter{
+				dp := DiscoveredPrinter{
 					ID:      fmt.Sprintf("network-%s", ip),
 					Name:    fmt.Sprintf("Printer at %s", ip),
 					Type:    "network",
 					Address: ip,
 					Port:    9100,
-				})
+				}
+				if info, err := QueryInfo(ip, 9100, 500*time.Millisecond); err == nil {
+					dp.Info = info
+					if info.ModelName != "" {
+						dp.Name = fmt.Sprintf("%s at %s", info.ModelName, ip)
+					}
+				}
+				discovered = append(discovered, dp)
 			}
 		}
 	}
@@ -136,7 +260,7 @@ func discoverNetworkPrinters() []DiscoveredPrinter {
 
 // isPortOpen checks if a port is open on a host
 func isPortOpen(host string, port int, timeout time.Duration) bool {
-	address := fmt.Sprintf("%s:%d", host, port)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
 	conn, err := net.DialTimeout("tcp", address, timeout)
 	if err != nil {
 		return false
@@ -146,49 +270,28 @@ func isPortOpen(host string, port int, timeout time.Duration) bool {
 }
 
 // buildTestReceipt creates ESC/POS commands for a test receipt
-func buildTestReceipt() []byte {
-	var data []byte
-
-	// Initialize printer
-	data = append(data, 0x1B, 0x40) // ESC @
-
-	// Center align
-	data = append(data, 0x1B, 0x61, 0x01) // ESC a 1
-
-	// Bold on
-	data = append(data, 0x1B, 0x45, 0x01) // ESC E 1
-
-	// Double size
-	data = append(data, 0x1D, 0x21, 0x11) // GS ! 0x11
-
-	data = append(data, []byte("JETSETGO\n")...)
-
-	// Normal size
-	data = append(data, 0x1D, 0x21, 0x00) // GS ! 0x00
-
-	// Bold off
-	data = append(data, 0x1B, 0x45, 0x00) // ESC E 0
-
-	data = append(data, []byte("Print Server\n")...)
-	data = append(data, []byte("-------------------\n")...)
-	data = append(data, []byte("\n")...)
-
-	// Left align
-	data = append(data, 0x1B, 0x61, 0x00) // ESC a 0
-
-	data = append(data, []byte("Test Print\n")...)
-	data = append(data, []byte(fmt.Sprintf("Time: %s\n", time.Now().Format("2006-01-02 15:04:05")))...)
-	data = append(data, []byte("\n")...)
-
-	// Center align
-	data = append(data, 0x1B, 0x61, 0x01) // ESC a 1
-
-	data = append(data, []byte("-------------------\n")...)
-	data = append(data, []byte("Printer OK!\n")...)
-	data = append(data, []byte("\n\n\n")...)
-
-	// Cut paper (partial cut)
-	data = append(data, 0x1D, 0x56, 0x42, 0x00) // GS V 66 0
-
-	return data
+func buildTestReceipt(profile escpos.Profile) []byte {
+	b := escpos.NewBuilder(profile)
+	b.Init()
+
+	b.Align(escpos.AlignCenter).Bold(true).Size(2, 2)
+	b.Line("JETSETGO")
+	b.Size(1, 1).Bold(false)
+	b.Line("Print Server")
+	b.Line("-------------------")
+	b.Newline()
+
+	b.Align(escpos.AlignLeft)
+	b.Line("Test Print")
+	b.Line(fmt.Sprintf("Time: %s", time.Now().Format("2006-01-02 15:04:05")))
+	b.Line(fmt.Sprintf("Profile: %s", profile.Name))
+	b.Newline()
+
+	b.Align(escpos.AlignCenter)
+	b.Line("-------------------")
+	b.Line("Printer OK!")
+	b.Feed(3)
+
+	b.Cut()
+	return b.Bytes()
 }
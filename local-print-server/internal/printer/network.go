@@ -3,7 +3,9 @@ package printer
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,9 +16,31 @@ type NetworkPrinter struct {
 	address string
 	port    int
 	conn    net.Conn
-	mu      sync.Mutex
+	mu      sync.Mutex  // Held while connected to the printer, which takes one connection at a time
+	busy    atomic.Bool // Set while printing, so health probes don't queue behind a long job
+
+	// stateMu guards name, address, pin and info, which change without a print in flight
+	stateMu sync.RWMutex
+	info    *PrinterInfo // Cached GS I identification
+	pin     Pin          // Identity used to follow the printer across address changes
+	write   WriteOptions
 }
 
+// WriteOptions control how print data is sent. Large jobs are written in chunks, each with
+// its own deadline, so a slow printer draining a big raster image doesn't trip a single
+// deadline for the whole job. Zero fields use the defaults.
+type WriteOptions struct {
+	ChunkSize    int           // Bytes per write (default 4096)
+	ChunkTimeout time.Duration // Deadline for each chunk (default 10s)
+	BytesPerSec  int           // Throttle for printers with small buffers; 0 = unthrottled
+}
+
+// Write defaults
+const (
+	defaultChunkSize    = 4096
+	defaultChunkTimeout = 10 * time.Second
+)
+
 // NewNetworkPrinter creates a new network printer
 func NewNetworkPrinter(id, name, address string, port int) *NetworkPrinter {
 	return &NetworkPrinter{
@@ -34,9 +58,18 @@ func (p *NetworkPrinter) ID() string {
 
 // Name returns the printer name
 func (p *NetworkPrinter) Name() string {
+	p.stateMu.RLock()
+	defer p.stateMu.RUnlock()
 	return p.name
 }
 
+// SetName renames the printer
+func (p *NetworkPrinter) SetName(name string) {
+	p.stateMu.Lock()
+	p.name = name
+	p.stateMu.Unlock()
+}
+
 // Type returns the printer type
 func (p *NetworkPrinter) Type() string {
 	return "network"
@@ -44,41 +77,167 @@ func (p *NetworkPrinter) Type() string {
 
 // Status returns the printer status
 func (p *NetworkPrinter) Status() string {
+	status, _ := p.Probe()
+	return status
+}
+
+// Probe returns the printer status along with every condition detected
+func (p *NetworkPrinter) Probe() (string, []string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	// Try to connect to check status
-	addr := fmt.Sprintf("%s:%d", p.address, p.port)
-	conn, err := net.DialTimeout("tcp", addr, 2*time.Second)
+	conn, err := net.DialTimeout("tcp", p.addr(), 2*time.Second)
 	if err != nil {
-		return "offline"
+		return StatusOffline, []string{StatusOffline}
 	}
-	conn.Close()
-	return "online"
+	defer conn.Close()
+
+	// Reachable; ask for the real-time status if the printer supports it
+	return queryRealtimeStatus(conn, 500*time.Millisecond)
 }
 
 // Print sends data to the printer
 func (p *NetworkPrinter) Print(data []byte) error {
+	return p.PrintTraced(data, nil)
+}
+
+// Busy reports whether a print is in progress
+func (p *NetworkPrinter) Busy() bool {
+	return p.busy.Load()
+}
+
+// PrintTraced sends data to the printer in chunks, reporting progress to trace (which may be nil).
+// trace.Cancelled is checked before each chunk and while throttled.
+func (p *NetworkPrinter) PrintTraced(data []byte, trace *PrintTrace) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.busy.Store(true)
+	defer p.busy.Store(false)
 
-	addr := fmt.Sprintf("%s:%d", p.address, p.port)
+	opts := p.WriteOptions()
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	timeout := opts.ChunkTimeout
+	if timeout <= 0 {
+		timeout = defaultChunkTimeout
+	}
 
-	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	conn, err := net.DialTimeout("tcp", p.addr(), 5*time.Second)
 	if err != nil {
-		return fmt.Errorf("failed to connect to printer: %w", err)
+		return fmt.Errorf("%w: %w", ErrConnect, err)
 	}
 	defer conn.Close()
 
-	// Set write deadline
-	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	trace.connected()
+
+	start := time.Now()
+	for written := 0; written < len(data); {
+		if trace.cancelled() {
+			return fmt.Errorf("%w (%d of %d bytes sent)", ErrCancelled, written, len(data))
+		}
+		chunk := data[written:min(written+chunkSize, len(data))]
+
+		conn.SetWriteDeadline(time.Now().Add(timeout))
+		n, err := conn.Write(chunk)
+		written += n
+		if err != nil {
+			return fmt.Errorf("%w: %w (%d of %d bytes sent)", ErrWrite, err, written, len(data))
+		}
+		trace.progress(written, len(data))
+
+		// Hold back until the average rate is within the throttle
+		if opts.BytesPerSec > 0 && written < len(data) {
+			due := start.Add(time.Duration(float64(written) / float64(opts.BytesPerSec) * float64(time.Second)))
+			if wait := time.Until(due); wait > 0 && !trace.wait(wait) {
+				return fmt.Errorf("%w (%d of %d bytes sent)", ErrCancelled, written, len(data))
+			}
+		}
+	}
+	if len(data) == 0 {
+		trace.progress(0, 0)
+	}
+
+	return nil
+}
 
-	_, err = conn.Write(data)
+// WriteOptions returns how print data is sent to the printer
+func (p *NetworkPrinter) WriteOptions() WriteOptions {
+	p.stateMu.RLock()
+	defer p.stateMu.RUnlock()
+	return p.write
+}
+
+// SetWriteOptions sets how print data is sent to the printer
+func (p *NetworkPrinter) SetWriteOptions(opts WriteOptions) {
+	p.stateMu.Lock()
+	p.write = opts
+	p.stateMu.Unlock()
+}
+
+// Identify queries the printer with GS I and caches the result
+func (p *NetworkPrinter) Identify() (*PrinterInfo, error) {
+	p.mu.Lock()
+	info, err := QueryInfo(p.Address(), p.port, 2*time.Second)
+	p.mu.Unlock()
 	if err != nil {
-		return fmt.Errorf("failed to send data to printer: %w", err)
+		return nil, err
 	}
 
-	return nil
+	p.stateMu.Lock()
+	p.info = info
+	p.stateMu.Unlock()
+	return info, nil
+}
+
+// Info returns the cached identification, or nil if the printer was never identified
+func (p *NetworkPrinter) Info() *PrinterInfo {
+	p.stateMu.RLock()
+	defer p.stateMu.RUnlock()
+	return p.info
+}
+
+// Address returns the printer's current IP address or hostname
+func (p *NetworkPrinter) Address() string {
+	p.stateMu.RLock()
+	defer p.stateMu.RUnlock()
+	return p.address
+}
+
+// Port returns the printer's TCP port
+func (p *NetworkPrinter) Port() int {
+	return p.port
+}
+
+// SetAddress points the printer at a new address
+func (p *NetworkPrinter) SetAddress(address string) {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+	p.address = address
+}
+
+// Pin returns the identity the printer is pinned by
+func (p *NetworkPrinter) Pin() Pin {
+	p.stateMu.RLock()
+	defer p.stateMu.RUnlock()
+	return p.pin
+}
+
+// SetPin sets the identity the printer is pinned by
+func (p *NetworkPrinter) SetPin(pin Pin) {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+	pin.MAC = NormalizeMAC(pin.MAC)
+	p.pin = pin
+}
+
+// addr returns the host:port dial address
+func (p *NetworkPrinter) addr() string {
+	p.stateMu.RLock()
+	defer p.stateMu.RUnlock()
+	return net.JoinHostPort(p.address, strconv.Itoa(p.port))
 }
 
 // Close closes the printer connection
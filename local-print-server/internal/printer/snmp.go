@@ -0,0 +1,234 @@
+package printer
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Printer-MIB prtGeneralSerialNumber for the first printer on the device
+const oidPrtSerialNumber = "1.3.6.1.2.1.43.5.1.1.17.1"
+
+// BER tags used by SNMPv1
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berNull        = 0x05
+	berOID         = 0x06
+	berSequence    = 0x30
+	berGetRequest  = 0xA0
+	berGetResponse = 0xA2
+)
+
+// snmpGetString performs a single SNMPv1 GET and returns the value as a string
+func snmpGetString(host, community, oid string, timeout time.Duration) (string, error) {
+	oidBytes, err := encodeOID(oid)
+	if err != nil {
+		return "", err
+	}
+
+	var idBuf [4]byte
+	rand.Read(idBuf[:])
+	requestID := int(binary.BigEndian.Uint32(idBuf[:]) & 0x7FFFFFFF)
+
+	varbind := berTLV(berSequence, append(berTLV(berOID, oidBytes), berNull, 0x00))
+	pdu := berTLV(berGetRequest, concat(
+		berInt(requestID),
+		berInt(0), // error-status
+		berInt(0), // error-index
+		berTLV(berSequence, varbind),
+	))
+	msg := berTLV(berSequence, concat(
+		berInt(0), // version-1
+		berTLV(berOctetString, []byte(community)),
+		pdu,
+	))
+
+	conn, err := net.DialTimeout("udp", net.JoinHostPort(host, "161"), timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(msg); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return parseGetResponse(buf[:n], requestID)
+}
+
+// parseGetResponse extracts the first varbind value from a GetResponse
+func parseGetResponse(data []byte, requestID int) (string, error) {
+	_, msg, _, err := berRead(data)
+	if err != nil {
+		return "", err
+	}
+
+	// version, community, PDU
+	_, _, rest, err := berRead(msg)
+	if err != nil {
+		return "", err
+	}
+	_, _, rest, err = berRead(rest)
+	if err != nil {
+		return "", err
+	}
+	tag, pdu, _, err := berRead(rest)
+	if err != nil {
+		return "", err
+	}
+	if tag != berGetResponse {
+		return "", fmt.Errorf("unexpected SNMP PDU type 0x%02x", tag)
+	}
+
+	// request-id, error-status, error-index, varbinds
+	_, id, rest, err := berRead(pdu)
+	if err != nil {
+		return "", err
+	}
+	if berToInt(id) != requestID {
+		return "", errors.New("SNMP response does not match request")
+	}
+	_, status, rest, err := berRead(rest)
+	if err != nil {
+		return "", err
+	}
+	if berToInt(status) != 0 {
+		return "", fmt.Errorf("SNMP error status %d", berToInt(status))
+	}
+	_, _, rest, err = berRead(rest)
+	if err != nil {
+		return "", err
+	}
+	_, varbinds, _, err := berRead(rest)
+	if err != nil {
+		return "", err
+	}
+	_, varbind, _, err := berRead(varbinds)
+	if err != nil {
+		return "", err
+	}
+	_, _, rest, err = berRead(varbind) // OID
+	if err != nil {
+		return "", err
+	}
+	tag, value, _, err := berRead(rest)
+	if err != nil {
+		return "", err
+	}
+	if tag != berOctetString {
+		return "", fmt.Errorf("SNMP value is not a string (type 0x%02x)", tag)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+// berRead reads one TLV, returning its tag, value and the remaining bytes
+func berRead(data []byte) (byte, []byte, []byte, error) {
+	if len(data) < 2 {
+		return 0, nil, nil, errors.New("truncated SNMP message")
+	}
+	tag := data[0]
+	length := int(data[1])
+	offset := 2
+	if length&0x80 != 0 {
+		n := length & 0x7F
+		if n == 0 || n > 4 || len(data) < 2+n {
+			return 0, nil, nil, errors.New("invalid SNMP length")
+		}
+		length = 0
+		for _, b := range data[2 : 2+n] {
+			length = length<<8 | int(b)
+		}
+		offset += n
+	}
+	if len(data) < offset+length {
+		return 0, nil, nil, errors.New("truncated SNMP message")
+	}
+	return tag, data[offset : offset+length], data[offset+length:], nil
+}
+
+// berTLV encodes a tag-length-value
+func berTLV(tag byte, value []byte) []byte {
+	out := []byte{tag}
+	n := len(value)
+	switch {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xFF:
+		out = append(out, 0x81, byte(n))
+	default:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	}
+	return append(out, value...)
+}
+
+// berInt encodes a non-negative INTEGER
+func berInt(v int) []byte {
+	var b []byte
+	for {
+		b = append([]byte{byte(v)}, b...)
+		v >>= 8
+		if v == 0 {
+			break
+		}
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0x00}, b...)
+	}
+	return berTLV(berInteger, b)
+}
+
+// berToInt decodes an INTEGER value
+func berToInt(b []byte) int {
+	v := 0
+	for _, c := range b {
+		v = v<<8 | int(c)
+	}
+	return v
+}
+
+// encodeOID encodes a dotted OID string
+func encodeOID(oid string) ([]byte, error) {
+	parts := strings.Split(oid, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID: %s", oid)
+	}
+	arcs := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid OID: %s", oid)
+		}
+		arcs[i] = n
+	}
+
+	out := []byte{byte(arcs[0]*40 + arcs[1])}
+	for _, arc := range arcs[2:] {
+		enc := []byte{byte(arc & 0x7F)}
+		for arc >>= 7; arc > 0; arc >>= 7 {
+			enc = append([]byte{byte(arc&0x7F) | 0x80}, enc...)
+		}
+		out = append(out, enc...)
+	}
+	return out, nil
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+package printer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// getResponse builds an SNMPv1 GetResponse with one varbind
+func getResponse(requestID, errStatus int, oid string, value []byte) []byte {
+	oidBytes, _ := encodeOID(oid)
+	varbind := berTLV(berSequence, concat(berTLV(berOID, oidBytes), value))
+	pdu := berTLV(berGetResponse, concat(
+		berInt(requestID),
+		berInt(errStatus),
+		berInt(0),
+		berTLV(berSequence, varbind),
+	))
+	return berTLV(berSequence, concat(berInt(0), berTLV(berOctetString, []byte("public")), pdu))
+}
+
+func TestEncodeOID(t *testing.T) {
+	tests := []struct {
+		oid  string
+		want []byte
+	}{
+		{oidPrtSerialNumber, []byte{0x2b, 6, 1, 2, 1, 43, 5, 1, 1, 17, 1}},
+		{"1.3.6.1.4.1.311", []byte{0x2b, 6, 1, 4, 1, 0x82, 0x37}},
+		{"1.3.16384", []byte{0x2b, 0x81, 0x80, 0x00}},
+	}
+	for _, tt := range tests {
+		got, err := encodeOID(tt.oid)
+		if err != nil || !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeOID(%s) = % x, %v; want % x", tt.oid, got, err, tt.want)
+		}
+	}
+	for _, oid := range []string{"1", "1.3.x", "1.-3"} {
+		if _, err := encodeOID(oid); err == nil {
+			t.Errorf("encodeOID(%q) succeeded", oid)
+		}
+	}
+}
+
+func TestBERLengths(t *testing.T) {
+	for _, n := range []int{0, 0x7f, 0x80, 0xff, 0x100, 1400} {
+		value := bytes.Repeat([]byte{'x'}, n)
+		tag, got, rest, err := berRead(append(berTLV(berOctetString, value), 0xAA))
+		if err != nil || tag != berOctetString || len(got) != n || !bytes.Equal(rest, []byte{0xAA}) {
+			t.Errorf("%d-byte value: tag 0x%02x, %d bytes, rest % x, err %v", n, tag, len(got), rest, err)
+		}
+	}
+	for _, v := range []int{0, 1, 127, 128, 255, 256, 0x7fffffff} {
+		_, b, _, _ := berRead(berInt(v))
+		if got := berToInt(b); got != v {
+			t.Errorf("berInt(%d) decodes to %d", v, got)
+		}
+	}
+}
+
+func TestParseGetResponse(t *testing.T) {
+	serial := berTLV(berOctetString, []byte(" X5E1234567 "))
+	got, err := parseGetResponse(getResponse(42, 0, oidPrtSerialNumber, serial), 42)
+	if err != nil || got != "X5E1234567" {
+		t.Errorf("got %q, %v; want X5E1234567", got, err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"other request", getResponse(43, 0, oidPrtSerialNumber, serial), "does not match"},
+		{"noSuchName", getResponse(42, 2, oidPrtSerialNumber, []byte{berNull, 0}), "error status 2"},
+		{"integer value", getResponse(42, 0, oidPrtSerialNumber, berInt(7)), "not a string"},
+		{"bad length", []byte{berSequence, 0x85, 1, 2, 3, 4, 5}, "invalid SNMP length"},
+	}
+	for _, tt := range tests {
+		if _, err := parseGetResponse(tt.data, 42); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	// Every truncation of a valid response fails cleanly
+	data := getResponse(42, 0, oidPrtSerialNumber, serial)
+	for i := 0; i < len(data); i++ {
+		if _, err := parseGetResponse(data[:i], 42); err == nil {
+			t.Errorf("response cut to %d bytes parsed", i)
+		}
+	}
+}
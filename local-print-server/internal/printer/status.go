@@ -0,0 +1,103 @@
+package printer
+
+import (
+	"net"
+	"time"
+)
+
+// Printer status values
+const (
+	StatusOnline    = "online"
+	StatusOffline   = "offline"
+	StatusPaperOut  = "paper_out"
+	StatusPaperLow  = "paper_low"
+	StatusCoverOpen = "cover_open"
+	StatusError     = "error"
+	StatusUnknown   = "unknown"
+
+	// StatusPaused is reported for printers paused by staff; probes never return it
+	StatusPaused = "paused"
+)
+
+// IsUp reports whether a printer in the given status can still print
+func IsUp(status string) bool {
+	return status == StatusOnline || status == StatusPaperLow
+}
+
+// DLE EOT n (transmit real-time status) parameters and response bits
+const (
+	dleEOTOffline = 0x02
+	dleEOTError   = 0x03
+	dleEOTPaper   = 0x04
+
+	offlineCoverOpen = 0x04
+	offlinePaperEnd  = 0x20
+	offlineError     = 0x40
+
+	errorUnrecoverable = 0x20
+	errorAutoRecover   = 0x40
+
+	paperNearEnd = 0x0C
+	paperEnd     = 0x60
+)
+
+// queryRealtimeStatus asks a connected printer for its status with DLE EOT.
+// It returns the primary status and every condition detected.
+// Printers that don't answer are reported as online, since they accepted the connection.
+func queryRealtimeStatus(conn net.Conn, timeout time.Duration) (string, []string) {
+	offline, ok := queryDLEEOT(conn, dleEOTOffline, timeout)
+	if !ok {
+		return StatusOnline, nil
+	}
+
+	var reasons []string
+	if offline&offlineCoverOpen != 0 {
+		reasons = append(reasons, StatusCoverOpen)
+	}
+
+	paper, paperOK := queryDLEEOT(conn, dleEOTPaper, timeout)
+	if (paperOK && paper&paperEnd != 0) || offline&offlinePaperEnd != 0 {
+		reasons = append(reasons, StatusPaperOut)
+	} else if paperOK && paper&paperNearEnd != 0 {
+		reasons = append(reasons, StatusPaperLow)
+	}
+
+	if offline&offlineError != 0 {
+		if errs, ok := queryDLEEOT(conn, dleEOTError, timeout); !ok || errs&(errorUnrecoverable|errorAutoRecover) != 0 {
+			reasons = append(reasons, StatusError)
+		}
+	}
+
+	return primaryStatus(reasons), reasons
+}
+
+// primaryStatus picks the most significant condition to report as the status
+func primaryStatus(reasons []string) string {
+	for _, s := range []string{StatusCoverOpen, StatusPaperOut, StatusError, StatusPaperLow} {
+		for _, r := range reasons {
+			if r == s {
+				return s
+			}
+		}
+	}
+	return StatusOnline
+}
+
+// queryDLEEOT sends DLE EOT n and reads the one-byte status response
+func queryDLEEOT(conn net.Conn, n byte, timeout time.Duration) (byte, bool) {
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write([]byte{0x10, 0x04, n}); err != nil {
+		return 0, false
+	}
+
+	buf := make([]byte, 1)
+	if _, err := conn.Read(buf); err != nil {
+		return 0, false
+	}
+
+	// Status bytes always have bit 4 set and bits 0, 1 and 7 clear
+	if buf[0]&0x93 != 0x12 {
+		return 0, false
+	}
+	return buf[0], true
+}
@@ -0,0 +1,134 @@
+package printer
+
+import (
+	"log"
+	"time"
+)
+
+// Tracker follows pinned network printers across DHCP address changes.
+// When a pinned printer stops answering at its configured address, the
+// tracker scans the local subnets for it and moves it to its new address.
+type Tracker struct {
+	mgr      *Manager
+	interval time.Duration
+	done     chan struct{}
+
+	// OnUpdated is called when a printer's address or learned pin changes,
+	// so the caller can persist it
+	OnUpdated func(printerID, address string, pin Pin)
+}
+
+// NewTracker creates a tracker that checks printers every interval
+func NewTracker(mgr *Manager, interval time.Duration) *Tracker {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Tracker{
+		mgr:      mgr,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start begins the background check loop
+func (t *Tracker) Start() {
+	go t.loop()
+}
+
+// Stop stops the background check loop
+func (t *Tracker) Stop() {
+	close(t.done)
+}
+
+func (t *Tracker) loop() {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			t.checkAll()
+		}
+	}
+}
+
+// checkAll checks every pinned network printer once
+func (t *Tracker) checkAll() {
+	for _, id := range t.mgr.ListPrinters() {
+		p, err := t.mgr.GetPrinter(id)
+		if err != nil {
+			continue
+		}
+		if np, ok := p.(*NetworkPrinter); ok && np.Pin().Pinned() {
+			t.check(np)
+		}
+	}
+}
+
+// check verifies a pinned printer is still reachable, relocating it if not
+func (t *Tracker) check(np *NetworkPrinter) {
+	pin := np.Pin()
+	address := np.Address()
+
+	h := t.mgr.Health(np.ID())
+	if h.CheckedAt.IsZero() {
+		h = t.mgr.CheckHealth(np.ID())
+	}
+
+	// Any status other than offline means the printer answered at its address
+	if h.Status != StatusOffline {
+		// Learn the identifier the first time the printer is seen
+		if pin.Value() == "" {
+			if v := identify(pin.By, address, np.Port()); v != "" {
+				switch pin.By {
+				case PinByMAC:
+					pin.MAC = v
+				case PinBySerial:
+					pin.Serial = v
+				case PinBySNMPSerial:
+					pin.SNMPSerial = v
+				}
+				np.SetPin(pin)
+				log.Printf("Printer %s pinned by %s: %s", np.ID(), pin.By, v)
+				t.notify(np.ID(), address, pin)
+			}
+			return
+		}
+
+		// A MAC pin is cheap to verify, so catch the address being reused by another device
+		if pin.By != PinByMAC {
+			return
+		}
+		if mac := arpLookupMAC(address); mac == "" || mac == pin.MAC {
+			return
+		}
+		log.Printf("Printer %s: %s now belongs to a different device", np.ID(), address)
+	}
+
+	if pin.Value() == "" {
+		return
+	}
+
+	log.Printf("Printer %s not answering at %s, searching by %s...", np.ID(), address, pin.By)
+	newAddress, err := Locate(pin, np.Port(), LocalSubnets(address))
+	if err != nil {
+		log.Printf("Printer %s could not be relocated: %v", np.ID(), err)
+		return
+	}
+	if newAddress == address {
+		return
+	}
+
+	np.SetAddress(newAddress)
+	log.Printf("Printer %s moved from %s to %s", np.ID(), address, newAddress)
+	t.mgr.CheckHealth(np.ID())
+	t.notify(np.ID(), newAddress, pin)
+}
+
+func (t *Tracker) notify(printerID, address string, pin Pin) {
+	if t.OnUpdated != nil {
+		t.OnUpdated(printerID, address, pin)
+	}
+}
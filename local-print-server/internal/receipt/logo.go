@@ -0,0 +1,51 @@
+package receipt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif" // Logo formats
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+)
+
+// dotsPerColumn is the width of a Font A character in dots
+const dotsPerColumn = 12
+
+// Logo limits. The logo is checked against them before it is decoded, since a small
+// file can declare an image big enough to exhaust memory.
+const (
+	maxLogoBytes = 1 << 20
+	maxLogoSide  = 2048 // Pixels, either side
+)
+
+// decodeLogo decodes a base64 PNG, JPEG or GIF, optionally given as a data: URI, of up
+// to maxLogoBytes and maxLogoSide pixels a side
+func decodeLogo(encoded string) (image.Image, error) {
+	if i := strings.Index(encoded, ";base64,"); strings.HasPrefix(encoded, "data:") && i >= 0 {
+		encoded = encoded[i+len(";base64,"):]
+	}
+	if base64.StdEncoding.DecodedLen(len(encoded)) > maxLogoBytes {
+		return nil, fmt.Errorf("logo is larger than %d bytes", maxLogoBytes)
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %w", err)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported image: %w", err)
+	}
+	if cfg.Width > maxLogoSide || cfg.Height > maxLogoSide {
+		return nil, fmt.Errorf("logo is %dx%d pixels; the most is %dx%d", cfg.Width, cfg.Height, maxLogoSide, maxLogoSide)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported image: %w", err)
+	}
+	return img, nil
+}
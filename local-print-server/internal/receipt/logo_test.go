@@ -0,0 +1,56 @@
+package receipt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeLogo(t *testing.T) {
+	raw := encodePNG(t, image.NewGray(image.Rect(0, 0, 40, 20)))
+	for _, encoded := range []string{
+		base64.StdEncoding.EncodeToString(raw),
+		"data:image/png;base64," + base64.StdEncoding.EncodeToString(raw),
+	} {
+		img, err := decodeLogo(encoded)
+		if err != nil {
+			t.Fatalf("decodeLogo: %v", err)
+		}
+		if b := img.Bounds(); b.Dx() != 40 || b.Dy() != 20 {
+			t.Errorf("logo is %v, want 40x20", b)
+		}
+	}
+}
+
+func TestDecodeLogoRejectsHugeImages(t *testing.T) {
+	// A tiny PNG whose header claims 30000x30000 pixels
+	raw := encodePNG(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+	ihdr := raw[12:29] // Chunk type and data
+	binary.BigEndian.PutUint32(ihdr[4:], 30000)
+	binary.BigEndian.PutUint32(ihdr[8:], 30000)
+	binary.BigEndian.PutUint32(raw[29:], crc32.ChecksumIEEE(ihdr))
+
+	_, err := decodeLogo(base64.StdEncoding.EncodeToString(raw))
+	if err == nil || !strings.Contains(err.Error(), "30000x30000") {
+		t.Errorf("err = %v, want the logo rejected for its size", err)
+	}
+
+	big := base64.StdEncoding.EncodeToString(make([]byte, maxLogoBytes+1))
+	if _, err := decodeLogo(big); err == nil {
+		t.Error("logo over maxLogoBytes was accepted")
+	}
+}
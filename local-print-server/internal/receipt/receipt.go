@@ -0,0 +1,215 @@
+// Package receipt renders structured receipt data (the cloud's JSON receipt format) to
+// ESC/POS locally, so formatted tickets can still be printed when the cloud is unreachable.
+package receipt
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jetsetgo/local-print-server/internal/escpos"
+	"github.com/jetsetgo/local-print-server/internal/markup"
+)
+
+// DefaultTemplate is used when a job names no template
+const DefaultTemplate = "receipt"
+
+// templates render a template's data for a printer
+var templates = map[string]func(data json.RawMessage, opts markup.Options) ([]byte, error){
+	"receipt": renderReceipt,
+}
+
+// Templates returns the known template names, sorted
+func Templates() []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render renders template data to ESC/POS for a printer. An empty template name is
+// DefaultTemplate.
+func Render(template string, data json.RawMessage, opts markup.Options) ([]byte, error) {
+	if template == "" {
+		template = DefaultTemplate
+	}
+	render, ok := templates[template]
+	if !ok {
+		return nil, fmt.Errorf("unknown template %q (use %s)", template, strings.Join(Templates(), ", "))
+	}
+	if len(data) == 0 || string(data) == "null" {
+		return nil, fmt.Errorf("template %s needs data", template)
+	}
+	return render(data, opts)
+}
+
+// Receipt is the data of the receipt template
+type Receipt struct {
+	Header   Header `json:"header"`
+	Items    []Item `json:"items"`
+	Totals   Totals `json:"totals"`
+	Footer   Footer `json:"footer"`
+	Currency string `json:"currency,omitempty"` // Prefix for amounts, e.g. "$"
+}
+
+// Header is printed centred at the top
+type Header struct {
+	Logo         string   `json:"logo,omitempty"` // Base64 PNG, JPEG or GIF, optionally a data: URI
+	BusinessName string   `json:"business_name,omitempty"`
+	Address      []string `json:"address,omitempty"`
+}
+
+// Item is a line item; its amount is qty x price
+type Item struct {
+	Name  string  `json:"name"`
+	Qty   float64 `json:"qty"`
+	Price float64 `json:"price"`
+}
+
+// Totals are printed as given; a missing total is the sum of the items
+type Totals struct {
+	Subtotal *float64 `json:"subtotal,omitempty"`
+	Tax      *float64 `json:"tax,omitempty"`
+	TaxLabel string   `json:"tax_label,omitempty"` // Default "Tax"
+	Total    *float64 `json:"total,omitempty"`
+}
+
+// Footer is printed centred at the bottom
+type Footer struct {
+	Barcode string `json:"barcode,omitempty"` // CODE128
+	QRCode  string `json:"qr_code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func renderReceipt(data json.RawMessage, opts markup.Options) ([]byte, error) {
+	var r Receipt
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid receipt data: %w", err)
+	}
+	for _, code := range []struct{ name, value string }{{"barcode", r.Footer.Barcode}, {"qr_code", r.Footer.QRCode}} {
+		if strings.ContainsAny(code.value, "{}\n") {
+			return nil, fmt.Errorf("footer %s must not contain braces or line breaks", code.name)
+		}
+	}
+
+	b := escpos.NewBuilder(opts.Profile)
+	if r.Header.Logo != "" {
+		logo, err := decodeLogo(r.Header.Logo)
+		if err != nil {
+			return nil, fmt.Errorf("header logo: %w", err)
+		}
+		if err := b.Init().Align(escpos.AlignCenter).Image(logo, opts.Columns*dotsPerColumn).Err(); err != nil {
+			return nil, fmt.Errorf("header logo: %w", err)
+		}
+	}
+
+	body, err := markup.Render(r.markup(opts.Columns), opts)
+	if err != nil {
+		return nil, err
+	}
+	return b.Raw(body).Bytes(), nil
+}
+
+// markup lays the receipt out as markup text for a paper width in columns
+func (r Receipt) markup(cols int) string {
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(s)
+		b.WriteByte('\n')
+	}
+
+	// Header
+	line("{center}")
+	if name := r.Header.BusinessName; name != "" {
+		if len([]rune(name))*2 <= cols {
+			line("{b}{big}" + escape(oneLine(name)) + "{/big}{/b}")
+		} else {
+			line("{b}" + escape(oneLine(name)) + "{/b}")
+		}
+	}
+	for _, addr := range r.Header.Address {
+		line(escape(oneLine(addr)))
+	}
+	line("{left}{hr}")
+
+	// Items
+	var sum float64
+	for _, item := range r.Items {
+		qty := item.Qty
+		if qty == 0 {
+			qty = 1
+		}
+		amount := qty * item.Price
+		sum += amount
+
+		name := oneLine(item.Name)
+		if qty != 1 {
+			name = fmt.Sprintf("%g x %s", qty, name)
+		}
+		line(escape(columns(name, r.money(amount), cols)))
+	}
+	line("{hr}")
+
+	// Totals
+	if t := r.Totals.Subtotal; t != nil {
+		line(escape(columns("Subtotal", r.money(*t), cols)))
+	}
+	if t := r.Totals.Tax; t != nil {
+		label := oneLine(r.Totals.TaxLabel)
+		if label == "" {
+			label = "Tax"
+		}
+		line(escape(columns(label, r.money(*t), cols)))
+	}
+	total := sum
+	if t := r.Totals.Total; t != nil {
+		total = *t
+	}
+	line("{b}" + escape(columns("TOTAL", r.money(total), cols)) + "{/b}")
+
+	// Footer
+	if r.Footer.Barcode != "" || r.Footer.QRCode != "" || r.Footer.Message != "" {
+		line("")
+		line("{center}")
+		if r.Footer.Barcode != "" {
+			line("{barcode CODE128 " + r.Footer.Barcode + "}")
+		}
+		if r.Footer.QRCode != "" {
+			line("{qr " + r.Footer.QRCode + "}")
+		}
+		for _, msg := range strings.Split(strings.ReplaceAll(r.Footer.Message, "\r\n", "\n"), "\n") {
+			if msg != "" {
+				line(escape(msg))
+			}
+		}
+	}
+	return b.String()
+}
+
+// money formats an amount with the receipt's currency prefix
+func (r Receipt) money(v float64) string {
+	return fmt.Sprintf("%s%.2f", r.Currency, v)
+}
+
+// columns puts left and right on one line of width characters, or right-aligns right on
+// a second line if they don't fit
+func columns(left, right string, width int) string {
+	gap := width - len([]rune(left)) - len([]rune(right))
+	if gap >= 1 {
+		return left + strings.Repeat(" ", gap) + right
+	}
+	return left + "\n" + strings.Repeat(" ", max(width-len([]rune(right)), 0)) + right
+}
+
+// oneLine replaces line breaks in a value with spaces
+func oneLine(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
+
+// escape makes text safe to embed in markup
+func escape(s string) string {
+	return strings.ReplaceAll(s, "{", "{{")
+}